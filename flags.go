@@ -1,6 +1,24 @@
 package main
 
 const (
-	lavalinkNodeFlagName  = "lavalink_node"
-	lavalinkNodesFlagName = "lavalink_nodes"
+	lavalinkNodeFlagName    = "lavalink_node"
+	lavalinkNodesFlagName   = "lavalink_nodes"
+	httpAddressFlagName     = "http_address"
+	apiAddressFlagName      = "api_address"
+	apiTokenFlagName        = "api_token"
+	aloneTimeoutFlagName    = "alone_timeout"
+	pausedTimeoutFlagName   = "paused_timeout"
+	autoPauseFlagName       = "auto_pause"
+	dataDirFlagName         = "data_dir"
+	lyricsURLFlagName       = "lyrics_url"
+	rateLimitsFlagName      = "rate_limits"
+	sameChannelFlagName     = "same_channel"
+	stageTopicFlagName      = "stage_topic"
+	voiceStatusFlagName     = "voice_status"
+	presenceFlagName        = "presence"
+	presenceTrackFlagName   = "presence_track"
+	presenceServersFlagName = "presence_servers"
+	shardingFlagName        = "sharding"
+	shardCountFlagName      = "shard_count"
+	shardIDsFlagName        = "shard_ids"
 )
@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"go-discord-music/pkg/bot"
+	"go-discord-music/pkg/lyrics"
+	"go-discord-music/pkg/ratelimit"
 	"go-discord-music/pkg/version"
 
 	"github.com/disgoorg/disgolink/v3/disgolink"
@@ -64,6 +66,101 @@ func buildApp() *cli.Command {
 					"Set to 0 to disable idle timeout. Default is 5 minutes.",
 				Value: 5 * time.Minute,
 			},
+			&cli.DurationFlag{
+				Name:    aloneTimeoutFlagName,
+				Usage:   "Time after which the bot will disconnect from a voice channel without listeners. Set to 0 to disable.",
+				Value:   5 * time.Minute,
+				Sources: cli.EnvVars("ALONE_TIMEOUT"),
+			},
+			&cli.DurationFlag{
+				Name:    pausedTimeoutFlagName,
+				Usage:   "Time after which the bot will disconnect when the player has been paused. Set to 0 to disable.",
+				Value:   15 * time.Minute,
+				Sources: cli.EnvVars("PAUSED_TIMEOUT"),
+			},
+			&cli.BoolFlag{
+				Name:    autoPauseFlagName,
+				Usage:   "Pause playback when all listeners leave the voice channel and resume it when one returns.",
+				Sources: cli.EnvVars("AUTO_PAUSE"),
+			},
+			&cli.BoolFlag{
+				Name:    sameChannelFlagName,
+				Usage:   "Require users to be in the bot's voice channel to control playback with commands such as /skip and /pause.",
+				Value:   true,
+				Sources: cli.EnvVars("SAME_CHANNEL"),
+			},
+			&cli.BoolFlag{
+				Name:    stageTopicFlagName,
+				Usage:   "Set the topic of the stage channel the bot plays in to the current track, starting the stage if needed.",
+				Sources: cli.EnvVars("STAGE_TOPIC"),
+			},
+			&cli.BoolFlag{
+				Name:    voiceStatusFlagName,
+				Usage:   "Set the status of the bot's voice channel to the current track. Requires the Set Voice Channel Status permission.",
+				Sources: cli.EnvVars("VOICE_STATUS"),
+			},
+			&cli.BoolFlag{
+				Name:    presenceFlagName,
+				Usage:   "Show what the bot plays as its activity, rendered from the presence templates.",
+				Sources: cli.EnvVars("PRESENCE"),
+			},
+			&cli.StringFlag{
+				Name:    presenceTrackFlagName,
+				Usage:   "Go template of the \"Listening to\" activity while playing in a single server, with .Title, .Author and .URI of the track. Empty shows no activity. Used with --presence.",
+				Value:   bot.DefaultTrackPresence,
+				Sources: cli.EnvVars("PRESENCE_TRACK"),
+			},
+			&cli.StringFlag{
+				Name:    presenceServersFlagName,
+				Usage:   "Go template of the \"Playing\" activity while playing in several servers, with .Players servers. Empty shows no activity. Used with --presence.",
+				Value:   bot.DefaultServersPresence,
+				Sources: cli.EnvVars("PRESENCE_SERVERS"),
+			},
+			&cli.BoolFlag{
+				Name:    shardingFlagName,
+				Usage:   "Connect to Discord with several shards instead of a single gateway, needed once the bot is in more than 2500 servers.",
+				Sources: cli.EnvVars("SHARDING"),
+			},
+			&cli.IntFlag{
+				Name:    shardCountFlagName,
+				Usage:   "Total number of shards across all processes when sharding. 0 uses the count recommended by Discord.",
+				Sources: cli.EnvVars("SHARD_COUNT"),
+			},
+			&cli.IntSliceFlag{
+				Name:    shardIDsFlagName,
+				Usage:   "Comma separated IDs of the shards run by this process when sharding, for example '0,1'. Runs all shards when empty, requires --shard_count otherwise.",
+				Sources: cli.EnvVars("SHARD_IDS"),
+			},
+			&cli.StringFlag{
+				Name:    dataDirFlagName,
+				Usage:   "Directory where settings such as 24/7 mode are stored. Settings are lost on restart when empty.",
+				Sources: cli.EnvVars("DATA_DIR"),
+			},
+			&cli.StringFlag{
+				Name:    lyricsURLFlagName,
+				Usage:   "Base URL (for example 'https://lrclib.net') of an LRCLIB compatible API used for lyrics when the Lavalink node lacks the LavaLyrics plugin. Disabled when empty.",
+				Sources: cli.EnvVars("LYRICS_URL"),
+			},
+			&cli.StringFlag{
+				Name:    rateLimitsFlagName,
+				Usage:   "Comma separated command rate limits per user, for example 'play=3/10s,skip=off,*=5/10s'. '*' applies to commands without their own limit. Overrides the built-in defaults.",
+				Sources: cli.EnvVars("RATE_LIMITS"),
+			},
+			&cli.StringFlag{
+				Name:    httpAddressFlagName,
+				Usage:   "Address (for example ':8080') of the HTTP listener serving Prometheus metrics on /metrics and health probes on /healthz and /readyz. Disabled when empty.",
+				Sources: cli.EnvVars("HTTP_ADDRESS"),
+			},
+			&cli.StringFlag{
+				Name:    apiAddressFlagName,
+				Usage:   "Address (for example '127.0.0.1:8081') of the control API used by external dashboards. Disabled when empty.",
+				Sources: cli.EnvVars("API_ADDRESS"),
+			},
+			&cli.StringFlag{
+				Name:    apiTokenFlagName,
+				Usage:   "Bearer token required by the control API. Required when the control API is enabled.",
+				Sources: cli.EnvVars("API_TOKEN"),
+			},
 		},
 		EnableShellCompletion: true,
 	}
@@ -90,6 +187,34 @@ func Run(_ context.Context, c *cli.Command) error {
 	logger.Infof("Starting go-discord-music bot version %s", version.Version)
 	botOptions := []bot.Option{
 		bot.WithIdleTimeout(c.Duration("idle_timeout")),
+		bot.WithAloneTimeout(c.Duration(aloneTimeoutFlagName)),
+		bot.WithPausedTimeout(c.Duration(pausedTimeoutFlagName)),
+		bot.WithAutoPause(c.Bool(autoPauseFlagName)),
+		bot.WithSameChannel(c.Bool(sameChannelFlagName)),
+		bot.WithStageTopic(c.Bool(stageTopicFlagName)),
+		bot.WithVoiceStatus(c.Bool(voiceStatusFlagName)),
+		bot.WithDataDir(c.String(dataDirFlagName)),
+		bot.WithHTTPAddress(c.String(httpAddressFlagName)),
+		bot.WithAPI(c.String(apiAddressFlagName), c.String(apiTokenFlagName)),
+	}
+	if c.Bool(presenceFlagName) {
+		botOptions = append(botOptions, bot.WithPresence(c.String(presenceTrackFlagName), c.String(presenceServersFlagName)))
+	}
+	if c.Bool(shardingFlagName) {
+		botOptions = append(botOptions, bot.WithSharding(bot.Sharding{
+			Count: c.Int(shardCountFlagName),
+			IDs:   c.IntSlice(shardIDsFlagName),
+		}))
+	}
+	if lyricsURL := c.String(lyricsURLFlagName); lyricsURL != "" {
+		botOptions = append(botOptions, bot.WithLyricsProvider(lyrics.LRCLIB{BaseURL: lyricsURL}))
+	}
+	if value := c.String(rateLimitsFlagName); value != "" {
+		limits, limitsErr := ratelimit.ParseLimits(value)
+		if limitsErr != nil {
+			return fmt.Errorf("error parsing rate limits: %w", limitsErr)
+		}
+		botOptions = append(botOptions, bot.WithRateLimits(limits))
 	}
 	nodeInfo := c.String(lavalinkNodeFlagName)
 	if nodeInfo != "" {
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-discord-music/pkg/store"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// alwaysOnRejoinDelay is how long the bot waits before rejoining a 24/7 channel it was disconnected from.
+var alwaysOnRejoinDelay = 5 * time.Second
+
+// alwaysOn returns the 24/7 configuration of the guild, or nil when 24/7 mode is disabled.
+func (b *Bot) alwaysOn(guildID snowflake.ID) *store.AlwaysOn {
+	return b.Store.Guild(guildID).AlwaysOn
+}
+
+// setAlwaysOn enables 24/7 mode for the guild with config, or disables it when config is nil.
+func (b *Bot) setAlwaysOn(guildID snowflake.ID, config *store.AlwaysOn) error {
+	if err := b.Store.UpdateGuild(guildID, func(settings *store.GuildSettings) error {
+		settings.AlwaysOn = config
+		return nil
+	}); err != nil {
+		return err
+	}
+	if config != nil {
+		b.clearIdle(guildID)
+	}
+	return nil
+}
+
+// startAlwaysOn joins the guild's 24/7 channel and starts the fallback when nothing is playing.
+func (b *Bot) startAlwaysOn(ctx context.Context, guildID snowflake.ID) {
+	config := b.alwaysOn(guildID)
+	if config == nil {
+		return
+	}
+	b.logger.Infof("joining 24/7 channel %s in guild %s", config.ChannelID, guildID)
+	if err := b.Music.JoinChannel(ctx, guildID, config.ChannelID); err != nil {
+		b.logger.Errorf("error joining 24/7 channel in guild %s: %v", guildID, err)
+		return
+	}
+	if _, err := b.Music.NowPlaying(guildID); err == nil {
+		return
+	}
+	b.playFallback(ctx, guildID)
+}
+
+// playFallback plays the guild's 24/7 fallback, if one is configured.
+func (b *Bot) playFallback(ctx context.Context, guildID snowflake.ID) {
+	config := b.alwaysOn(guildID)
+	if config == nil || config.Fallback == "" {
+		return
+	}
+	b.logger.Infof("queue empty, playing 24/7 fallback in guild %s", guildID)
+	if _, err := b.Music.Play(ctx, guildID, config.ChannelID, 0, config.Fallback, ""); err != nil {
+		b.logger.Errorf("error playing 24/7 fallback in guild %s: %v", guildID, err)
+	}
+}
+
+// rejoinAlwaysOn rejoins the guild's 24/7 channel after the bot was disconnected, unless the bot is shutting down.
+func (b *Bot) rejoinAlwaysOn(guildID snowflake.ID) {
+	if b.alwaysOn(guildID) == nil {
+		return
+	}
+	b.logger.Infof("disconnected from 24/7 channel in guild %s, rejoining in %s", guildID, alwaysOnRejoinDelay)
+	time.AfterFunc(alwaysOnRejoinDelay, func() {
+		select {
+		case <-b.shutdown:
+			return
+		default:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		b.startAlwaysOn(ctx, guildID)
+	})
+}
+
+// rearmIdle resumes idle tracking for a guild whose 24/7 mode was disabled while the bot is in a voice channel.
+func (b *Bot) rearmIdle(ctx context.Context, guildID snowflake.ID) {
+	if selfState, ok := b.Client.Caches().VoiceState(guildID, b.Client.ApplicationID()); !ok || selfState.ChannelID == nil {
+		return
+	}
+	b.Music.RearmIdle(guildID)
+	b.checkListeners(ctx, guildID)
+}
+
+func (b *Bot) onGuildReady(event *events.GuildReady) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	b.startAlwaysOn(ctx, event.Guild.ID)
+}
+
+func (b *Bot) alwaysOnCommand(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	guildID := *event.GuildID()
+	if !data.Bool("enabled") {
+		if err := b.setAlwaysOn(guildID, nil); err != nil {
+			return serviceError("disabling 24/7 mode", err)
+		}
+		b.rearmIdle(ctx, guildID)
+		return event.CreateMessage(discord.MessageCreate{
+			Content: "24/7 mode disabled",
+		})
+	}
+
+	var channelID snowflake.ID
+	if channel, ok := data.OptChannel("channel"); ok {
+		channelID = channel.ID
+	} else {
+		userChannel, err := b.Music.UserVoiceChannel(guildID, event.User().ID)
+		if err != nil {
+			return userErrorf("Select a voice channel or join one to enable 24/7 mode")
+		}
+		channelID = userChannel
+	}
+
+	config := &store.AlwaysOn{ChannelID: channelID, Fallback: data.String("fallback")}
+	if err := b.setAlwaysOn(guildID, config); err != nil {
+		return serviceError("enabling 24/7 mode", err)
+	}
+	if err := event.DeferCreateMessage(false); err != nil {
+		return err
+	}
+
+	b.startAlwaysOn(ctx, guildID)
+
+	content := fmt.Sprintf("24/7 mode enabled in <#%s>", channelID)
+	if config.Fallback != "" {
+		content += fmt.Sprintf(", falling back to `%s` when the queue is empty", config.Fallback)
+	}
+	if !b.Store.Persistent() {
+		content += "\nNote: no data directory is configured, this setting is lost when the bot restarts"
+	}
+	_, err := b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: &content,
+	})
+	return err
+}
@@ -0,0 +1,105 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/store"
+
+	"github.com/Cyb3r-Jak3/common/v5"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFallback = "https://example.com/radio"
+
+func Test_AlwaysOn_ExemptFromIdle(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.bot.IdleTimeout = time.Minute
+	f.bot.markIdle(testGuildID, idleReasonNoTrack)
+	require.Contains(t, f.bot.idleTimers.remaining(), testGuildID)
+
+	require.NoError(t, f.bot.setAlwaysOn(testGuildID, &store.AlwaysOn{ChannelID: testChannelID}))
+	assert.NotContains(t, f.bot.idleTimers.remaining(), testGuildID, "enabling 24/7 mode cancels idle timers")
+
+	f.bot.markIdle(testGuildID, idleReasonAlone)
+	assert.NotContains(t, f.bot.idleTimers.remaining(), testGuildID)
+}
+
+func Test_AlwaysOn_PlaysFallbackWhenQueueEmpties(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.server.AddTrack("https://example.com/a", testTrack("a"))
+	f.server.AddTrack(testFallback, testTrack("radio"))
+	require.NoError(t, f.bot.setAlwaysOn(testGuildID, &store.AlwaysOn{ChannelID: testChannelID, Fallback: testFallback}))
+	events, cancel := f.bot.playerEvents.Subscribe()
+	defer cancel()
+
+	_, err := f.bot.Music.Play(context.Background(), testGuildID, testChannelID, testUserID, "https://example.com/a", "")
+	require.NoError(t, err)
+	waitForEvent(t, events, PlayerEventTrackStart)
+
+	require.NoError(t, f.server.FinishTrack(testGuildID, lavalink.TrackEndReasonFinished))
+	event := waitForEvent(t, events, PlayerEventTrackStart)
+	assert.Equal(t, "radio", event.Track.Encoded)
+}
+
+func Test_Gateway_AlwaysOnEnable(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.server.AddTrack(testFallback, lavalink.Track{Encoded: "radio", Info: lavalink.TrackInfo{Title: "Radio", URI: common.Ptr(testFallback)}})
+
+	f.replay("always_on_enable")
+
+	f.discord.AssertGolden("always_on_enable")
+	assert.Equal(t, &store.AlwaysOn{ChannelID: testChannelID, Fallback: testFallback}, f.bot.alwaysOn(testGuildID))
+	assert.Equal(t, "radio", f.server.CurrentTrack(testGuildID))
+}
+
+func Test_Gateway_AlwaysOnBlocksDisconnect(t *testing.T) {
+	f := newGatewayFixture(t)
+	require.NoError(t, f.bot.setAlwaysOn(testGuildID, &store.AlwaysOn{ChannelID: testChannelID}))
+
+	f.replay("disconnect")
+
+	f.discord.AssertGolden("always_on_disconnect")
+}
+
+func Test_Gateway_AlwaysOnRejoinsAfterKick(t *testing.T) {
+	delay := alwaysOnRejoinDelay
+	alwaysOnRejoinDelay = 0
+	t.Cleanup(func() { alwaysOnRejoinDelay = delay })
+
+	f := newGatewayFixture(t)
+	require.NoError(t, f.bot.setAlwaysOn(testGuildID, &store.AlwaysOn{ChannelID: testChannelID}))
+	f.replay("bot_joined")
+
+	f.replay("bot_left")
+
+	assert.Eventually(t, func() bool {
+		for _, sent := range f.discord.Gateway.Sent() {
+			if update, ok := sent.Data.(gateway.MessageDataVoiceStateUpdate); ok && update.ChannelID != nil && *update.ChannelID == testChannelID {
+				return true
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_Gateway_AlwaysOnDisableRearmsIdle(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.IdleTimeout = time.Minute
+	f.bot.AloneTimeout = time.Minute
+	require.NoError(t, f.bot.setAlwaysOn(testGuildID, &store.AlwaysOn{ChannelID: testChannelID}))
+	f.replay("bot_joined")
+	require.NotContains(t, f.bot.idleTimers.remaining(), testGuildID)
+
+	f.replay("always_on_disable")
+
+	f.discord.AssertGolden("always_on_disable")
+	assert.Nil(t, f.bot.alwaysOn(testGuildID))
+	remaining := f.bot.idleTimers.remaining()[testGuildID]
+	assert.Contains(t, remaining, idleReasonNoTrack)
+	assert.Contains(t, remaining, idleReasonAlone)
+}
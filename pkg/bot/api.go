@@ -0,0 +1,405 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-discord-music/pkg/policy"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/gorilla/websocket"
+)
+
+const apiRequestTimeout = 10 * time.Second
+
+// apiLoadRequest loads tracks on behalf of the user with UserID, whose queue policy limits apply to them. Without
+// a user the tracks have no requester and only the limits of the whole queue apply.
+type apiLoadRequest struct {
+	Identifier string        `json:"identifier"`
+	Source     string        `json:"source"`
+	ChannelID  *snowflake.ID `json:"channel_id"`
+	UserID     snowflake.ID  `json:"user_id"`
+}
+
+type apiSkipRequest struct {
+	Amount int `json:"amount"`
+}
+
+type apiPauseRequest struct {
+	Paused *bool `json:"paused"`
+}
+
+type apiVolumeRequest struct {
+	Volume int `json:"volume"`
+}
+
+type apiSeekRequest struct {
+	Position lavalink.Duration `json:"position"`
+}
+
+type apiQueueTypeRequest struct {
+	Type QueueType `json:"type"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+var apiUpgrader = websocket.Upgrader{
+	// Requests are authenticated by token, so cross-origin dashboards are allowed to connect.
+	CheckOrigin: func(_ *http.Request) bool { return true },
+}
+
+// startAPIServer starts the control API listener. It is a no-op when no address is configured.
+func (b *Bot) startAPIServer() {
+	if b.APIAddress == "" {
+		return
+	}
+	b.apiServer = &http.Server{
+		Addr:              b.APIAddress,
+		Handler:           b.requireAPIToken(b.apiRoutes()),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		b.logger.Infof("starting control API on %s", b.APIAddress)
+		if err := b.apiServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			b.logger.Errorf("error running control API: %v", err)
+		}
+	}()
+}
+
+func (b *Bot) stopAPIServer(ctx context.Context) {
+	if b.apiServer == nil {
+		return
+	}
+	if err := b.apiServer.Shutdown(ctx); err != nil {
+		b.logger.Errorf("error shutting down control API: %v", err)
+	}
+}
+
+func (b *Bot) apiRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/events", b.apiEvents)
+	mux.HandleFunc("GET /api/v1/guilds/{guildID}/player", b.apiGuild(b.apiGetPlayer))
+	mux.HandleFunc("POST /api/v1/guilds/{guildID}/play", b.apiGuild(b.apiPlay))
+	mux.HandleFunc("POST /api/v1/guilds/{guildID}/skip", b.apiGuild(b.apiSkip))
+	mux.HandleFunc("POST /api/v1/guilds/{guildID}/pause", b.apiGuild(b.apiPause))
+	mux.HandleFunc("POST /api/v1/guilds/{guildID}/stop", b.apiGuild(b.apiStop))
+	mux.HandleFunc("PUT /api/v1/guilds/{guildID}/volume", b.apiGuild(b.apiVolume))
+	mux.HandleFunc("POST /api/v1/guilds/{guildID}/seek", b.apiGuild(b.apiSeek))
+	mux.HandleFunc("PUT /api/v1/guilds/{guildID}/filters", b.apiGuild(b.apiFilters))
+	mux.HandleFunc("GET /api/v1/guilds/{guildID}/queue", b.apiGuild(b.apiGetQueue))
+	mux.HandleFunc("POST /api/v1/guilds/{guildID}/queue", b.apiGuild(b.apiAddToQueue))
+	mux.HandleFunc("DELETE /api/v1/guilds/{guildID}/queue", b.apiGuild(b.apiClearQueue))
+	mux.HandleFunc("DELETE /api/v1/guilds/{guildID}/queue/{index}", b.apiGuild(b.apiRemoveFromQueue))
+	mux.HandleFunc("POST /api/v1/guilds/{guildID}/queue/shuffle", b.apiGuild(b.apiShuffleQueue))
+	mux.HandleFunc("PUT /api/v1/guilds/{guildID}/queue/type", b.apiGuild(b.apiSetQueueType))
+	return mux
+}
+
+// requireAPIToken rejects requests which do not carry the API token, either as a bearer token
+// or, for WebSocket clients which cannot set headers, as the token query parameter.
+func (b *Bot) requireAPIToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			token = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(b.apiToken)) != 1 {
+			b.writeJSON(w, http.StatusUnauthorized, apiError{Error: "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// apiGuild parses the guildID path value and passes it to handler along with a request scoped context.
+func (b *Bot) apiGuild(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		guildID, err := snowflake.Parse(r.PathValue("guildID"))
+		if err != nil {
+			b.writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid guild ID"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), apiRequestTimeout)
+		defer cancel()
+		handler(ctx, w, r, guildID)
+	}
+}
+
+func (b *Bot) writeAPIError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNoPlayer), errors.Is(err, ErrNoTrack), errors.Is(err, ErrNoTracks), errors.Is(err, ErrNoMatches):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrInvalidQueueType), errors.Is(err, ErrQueueIndexInvalid), errors.Is(err, ErrSeekOutOfRange),
+		errors.Is(err, ErrNotSeekable), errors.Is(err, ErrUnsupportedSource), errors.Is(err, ErrUnsupportedFilter):
+		status = http.StatusBadRequest
+	case errors.Is(err, policy.ErrRejected), errors.Is(err, ErrNotInBotChannel):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrNotInVoice):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrChannelBusy):
+		status = http.StatusConflict
+	case errors.Is(err, discord.ErrShardNotFound):
+		// The guild is on a shard run by another process.
+		status = http.StatusMisdirectedRequest
+	default:
+		b.logger.Errorf("error handling API request: %v", err)
+	}
+	b.writeJSON(w, status, apiError{Error: err.Error()})
+}
+
+func (b *Bot) decodeAPIRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		b.writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func (b *Bot) writePlayerState(w http.ResponseWriter, guildID snowflake.ID) {
+	state, err := b.Music.State(guildID)
+	if err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writeJSON(w, http.StatusOK, state)
+}
+
+func (b *Bot) apiGetPlayer(_ context.Context, w http.ResponseWriter, _ *http.Request, guildID snowflake.ID) {
+	b.writePlayerState(w, guildID)
+}
+
+func (b *Bot) apiPlay(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	var req apiLoadRequest
+	if !b.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	channelID, err := b.apiPlayChannel(guildID, req)
+	if err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	if channelID == 0 {
+		b.writeJSON(w, http.StatusBadRequest, apiError{Error: "channel_id or user_id is required when the bot is not connected"})
+		return
+	}
+
+	result, err := b.Music.Play(ctx, guildID, channelID, req.UserID, req.Identifier, req.Source)
+	if err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writeJSON(w, http.StatusOK, result)
+}
+
+// apiPlayChannel returns the voice channel to play in: the requested one, the voice channel of the requesting user or
+// the bot's current one, in that order. Like /play, it won't take the bot away from others still listening.
+func (b *Bot) apiPlayChannel(guildID snowflake.ID, req apiLoadRequest) (snowflake.ID, error) {
+	switch {
+	case req.ChannelID != nil:
+		return *req.ChannelID, b.Music.CanJoin(guildID, *req.ChannelID)
+	case req.UserID != 0:
+		return b.Music.JoinableChannel(guildID, req.UserID)
+	}
+	if state, err := b.Music.State(guildID); err == nil && state.ChannelID != nil {
+		return *state.ChannelID, nil
+	}
+	return 0, nil
+}
+
+func (b *Bot) apiSkip(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	req := apiSkipRequest{Amount: 1}
+	if r.ContentLength != 0 && !b.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if req.Amount < 1 {
+		b.writeJSON(w, http.StatusBadRequest, apiError{Error: "amount must be at least 1"})
+		return
+	}
+	result, err := b.Music.Skip(ctx, guildID, req.Amount)
+	if err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writeJSON(w, http.StatusOK, result)
+}
+
+func (b *Bot) apiPause(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	var req apiPauseRequest
+	if r.ContentLength != 0 && !b.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if _, err := b.Music.Pause(ctx, guildID, req.Paused); err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writePlayerState(w, guildID)
+}
+
+func (b *Bot) apiStop(ctx context.Context, w http.ResponseWriter, _ *http.Request, guildID snowflake.ID) {
+	if err := b.Music.Stop(ctx, guildID); err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writePlayerState(w, guildID)
+}
+
+func (b *Bot) apiVolume(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	var req apiVolumeRequest
+	if !b.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if req.Volume < 0 || req.Volume > 1000 {
+		b.writeJSON(w, http.StatusBadRequest, apiError{Error: "volume must be between 0 and 1000"})
+		return
+	}
+	if err := b.Music.SetVolume(ctx, guildID, req.Volume); err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writePlayerState(w, guildID)
+}
+
+func (b *Bot) apiSeek(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	var req apiSeekRequest
+	if !b.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if err := b.Music.Seek(ctx, guildID, req.Position); err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writePlayerState(w, guildID)
+}
+
+func (b *Bot) apiFilters(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	var filters lavalink.Filters
+	if !b.decodeAPIRequest(w, r, &filters) {
+		return
+	}
+	err := b.Music.UpdateFilters(ctx, guildID, func(current *lavalink.Filters) {
+		*current = filters
+	})
+	if err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writePlayerState(w, guildID)
+}
+
+func (b *Bot) apiGetQueue(_ context.Context, w http.ResponseWriter, _ *http.Request, guildID snowflake.ID) {
+	b.writeJSON(w, http.StatusOK, b.Music.Queue(guildID).Tracks)
+}
+
+func (b *Bot) apiAddToQueue(ctx context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	var req apiLoadRequest
+	if !b.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if _, _, err := b.Music.Enqueue(ctx, guildID, req.UserID, req.Identifier, req.Source); err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writeJSON(w, http.StatusOK, b.Music.Queue(guildID).Tracks)
+}
+
+func (b *Bot) apiClearQueue(_ context.Context, w http.ResponseWriter, _ *http.Request, guildID snowflake.ID) {
+	b.Music.ClearQueue(guildID)
+	b.writeJSON(w, http.StatusOK, b.Music.Queue(guildID).Tracks)
+}
+
+func (b *Bot) apiRemoveFromQueue(_ context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		b.writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid queue index"})
+		return
+	}
+	if _, err = b.Music.RemoveFromQueue(guildID, index); err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writeJSON(w, http.StatusOK, b.Music.Queue(guildID).Tracks)
+}
+
+func (b *Bot) apiShuffleQueue(_ context.Context, w http.ResponseWriter, _ *http.Request, guildID snowflake.ID) {
+	b.Music.Shuffle(guildID)
+	b.writeJSON(w, http.StatusOK, b.Music.Queue(guildID).Tracks)
+}
+
+func (b *Bot) apiSetQueueType(_ context.Context, w http.ResponseWriter, r *http.Request, guildID snowflake.ID) {
+	var req apiQueueTypeRequest
+	if !b.decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if err := b.Music.SetQueueType(guildID, req.Type); err != nil {
+		b.writeAPIError(w, err)
+		return
+	}
+	b.writeJSON(w, http.StatusOK, req)
+}
+
+// apiEvents streams player events over a WebSocket. The optional guild_id query parameter limits the stream to one guild.
+func (b *Bot) apiEvents(w http.ResponseWriter, r *http.Request) {
+	var guildFilter snowflake.ID
+	if rawGuildID := r.URL.Query().Get("guild_id"); rawGuildID != "" {
+		var err error
+		if guildFilter, err = snowflake.Parse(rawGuildID); err != nil {
+			b.writeJSON(w, http.StatusBadRequest, apiError{Error: "invalid guild ID"})
+			return
+		}
+	}
+
+	conn, err := apiUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		b.logger.Errorf("error upgrading event stream: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	stream, cancel := b.playerEvents.Subscribe()
+	defer cancel()
+
+	// Reading is required to process close and ping frames from the client.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, readErr := conn.ReadMessage(); readErr != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(30 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-closed:
+			return
+		case <-b.shutdown:
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "shutting down"), time.Now().Add(time.Second))
+			return
+		case <-ping.C:
+			if err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(5*time.Second)); err != nil {
+				return
+			}
+		case event := <-stream:
+			if guildFilter != 0 && event.GuildID != guildFilter {
+				continue
+			}
+			if err = conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
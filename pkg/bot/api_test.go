@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-discord-music/pkg/policy"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_RequireAPIToken(t *testing.T) {
+	b := &Bot{apiToken: "secret", logger: logrus.New()}
+	handler := b.requireAPIToken(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		query  string
+		status int
+	}{
+		{name: "missing token", status: http.StatusUnauthorized},
+		{name: "wrong token", header: "Bearer nope", status: http.StatusUnauthorized},
+		{name: "bearer token", header: "Bearer secret", status: http.StatusNoContent},
+		{name: "query token", query: "?token=secret", status: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/events"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func Test_APIGuild_RejectsInvalidGuildID(t *testing.T) {
+	b := &Bot{logger: logrus.New()}
+	called := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /guilds/{guildID}", b.apiGuild(func(_ context.Context, w http.ResponseWriter, _ *http.Request, guildID snowflake.ID) {
+		called = true
+		assert.Equal(t, snowflake.ID(123), guildID)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/guilds/abc", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.False(t, called)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/guilds/123", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.True(t, called)
+}
+
+// api sends a request with a JSON body to the control API of the fixture's bot.
+func (f *gatewayFixture) api(method string, path string, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	f.bot.apiRoutes().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func Test_Gateway_APIPlayKeepsBotWithListeners(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.replay("bot_joined")
+	f.replay("listener_joined")
+
+	rec := f.api(http.MethodPost, "/api/v1/guilds/1/play", `{"identifier":"https://example.com/a","channel_id":"4"}`)
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error":"`+ErrChannelBusy.Error()+`"}`, rec.Body.String())
+	assert.Equal(t, testChannelID, *f.bot.botChannel(testGuildID))
+}
+
+func Test_Gateway_APIPlayRequiresUserInVoice(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	rec := f.api(http.MethodPost, "/api/v1/guilds/1/play", `{"identifier":"https://example.com/a","user_id":"5"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error":"`+ErrNotInVoice.Error()+`"}`, rec.Body.String())
+}
+
+func Test_Gateway_APITracksHaveRequester(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.replay("bot_joined")
+	f.replay("listener_joined")
+	f.server.AddTrack("https://example.com/b", lavalink.Track{Encoded: "b", Info: lavalink.TrackInfo{Title: "b", Length: lavalink.Minute}})
+
+	rec := f.api(http.MethodPost, "/api/v1/guilds/1/play", `{"identifier":"https://example.com/b","user_id":"2"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = f.api(http.MethodPost, "/api/v1/guilds/1/queue", `{"identifier":"https://example.com/b"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	tracks := f.bot.Queues.Get(testGuildID).Tracks
+	require.Len(t, tracks, 2)
+	assert.Equal(t, testUserID, policy.Requester(tracks[0]))
+	assert.Zero(t, policy.Requester(tracks[1]), "tracks added without a user have no requester")
+}
+
+func Test_WriteAPIError(t *testing.T) {
+	b := &Bot{logger: logrus.New()}
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{err: ErrNoPlayer, status: http.StatusNotFound},
+		{err: ErrQueueIndexInvalid, status: http.StatusBadRequest},
+		{err: ErrNotInVoice, status: http.StatusBadRequest},
+		{err: ErrNotInBotChannel, status: http.StatusForbidden},
+		{err: ErrChannelBusy, status: http.StatusConflict},
+		{err: errors.New("boom"), status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		b.writeAPIError(rec, tt.err)
+		assert.Equal(t, tt.status, rec.Code, tt.err.Error())
+	}
+}
+
+func Test_Gateway_APIPausePublishesEvents(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	events, cancel := f.bot.playerEvents.Subscribe()
+	defer cancel()
+
+	rec := f.api(http.MethodPost, "/api/v1/guilds/1/pause", `{"paused":true}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, testGuildID, waitForEvent(t, events, PlayerEventPause).GuildID)
+
+	rec = f.api(http.MethodPost, "/api/v1/guilds/1/pause", `{"paused":false}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, testGuildID, waitForEvent(t, events, PlayerEventResume).GuildID)
+}
+
+func Test_Gateway_APISkipAmount(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.bot.Queues.Get(testGuildID).Add(testTrack("b"))
+
+	for _, body := range []string{`{"amount":0}`, `{"amount":-1}`} {
+		rec := f.api(http.MethodPost, "/api/v1/guilds/1/skip", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	rec := f.api(http.MethodPost, "/api/v1/guilds/1/skip", `{"amount":1}`)
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Zero(t, f.bot.Queues.Get(testGuildID).Len())
+}
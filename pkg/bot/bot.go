@@ -4,10 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"go-discord-music/pkg/lyrics"
+	"go-discord-music/pkg/metrics"
+	"go-discord-music/pkg/ratelimit"
+	"go-discord-music/pkg/store"
 	"go-discord-music/pkg/version"
 	"log/slog"
+	"maps"
 	"net/http"
 	"net/http/cookiejar"
+	"runtime/debug"
 	"time"
 
 	"github.com/disgoorg/disgo"
@@ -15,35 +21,50 @@ import (
 	"github.com/disgoorg/disgo/cache"
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
-	"github.com/disgoorg/disgo/gateway"
 	"github.com/disgoorg/disgolink/v3/disgolink"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/sirupsen/logrus"
 )
 
+// CommandHandler handles an application command. ctx is cancelled once the command's timeout has passed.
+type CommandHandler func(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error
+
 type Bot struct {
-	Client      bot.Client
-	Lavalink    disgolink.Client
-	Handlers    map[string]func(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error
-	Queues      *QueueManager
-	HTTPClient  *http.Client
-	logger      *logrus.Logger
-	VersionInfo string
-	IdleTimeout time.Duration
-	idleTimes   map[snowflake.ID]time.Time
-	shutdown    chan struct{}
+	Client        bot.Client
+	Lavalink      disgolink.Client
+	Handlers      map[string]CommandHandler
+	Queues        *QueueManager
+	Music         *MusicService
+	Store         *store.Store
+	Lyrics        lyrics.Provider
+	HTTPClient    *http.Client
+	cdnClient     *http.Client
+	logger        *logrus.Logger
+	VersionInfo   string
+	IdleTimeout   time.Duration
+	HTTPAddress   string
+	APIAddress    string
+	apiToken      string
+	AloneTimeout  time.Duration
+	PausedTimeout time.Duration
+	idleTimers    *idleScheduler
+	httpServer    *http.Server
+	apiServer     *http.Server
+	playerEvents  *eventHub
+	capabilities  *capabilityRegistry
+	RateLimits    ratelimit.Limits
+	StageTopic    bool
+	Sharding      *Sharding
+	VoiceStatus   bool
+	voiceStatuses *throttledUpdates[snowflake.ID, string]
+	Presence      *PresenceTemplates
+	presence      *throttledUpdates[int, presenceActivity]
+	rateLimiter   *ratelimit.Limiter
+	shutdown      chan struct{}
 }
 
 func NewBot(Token string, logger *logrus.Logger, opts ...Option) (*Bot, error) {
-	b := &Bot{
-		Queues: &QueueManager{
-			queues: make(map[snowflake.ID]*Queue),
-		},
-		logger:      logger,
-		VersionInfo: version.String(),
-		idleTimes:   make(map[snowflake.ID]time.Time),
-		shutdown:    make(chan struct{}),
-	}
+	b := newBot(logger)
 
 	// Cookie jar is needed as the default Lavalink node is proxied and uses sticky session
 	jar, err := cookiejar.New(nil)
@@ -55,42 +76,96 @@ func NewBot(Token string, logger *logrus.Logger, opts ...Option) (*Bot, error) {
 	}
 	b.HTTPClient = httpClient
 
-	client, err := disgo.New(Token,
-		bot.WithGatewayConfigOpts(
-			gateway.WithIntents(gateway.IntentGuilds, gateway.IntentGuildVoiceStates),
-		),
-		bot.WithCacheConfigOpts(
-			cache.WithCaches(cache.FlagVoiceStates),
-		),
-		bot.WithEventListenerFunc(b.onApplicationCommand),
-		bot.WithEventListenerFunc(b.onVoiceStateUpdate),
-		bot.WithEventListenerFunc(b.onVoiceServerUpdate),
-	)
-
+	applicationID, err := applicationIDFromToken(Token)
 	if err != nil {
 		return nil, errors.Join(fmt.Errorf("error creating the bot client"), err)
 	}
 
+	b.Lavalink = b.newLavalinkClient(applicationID)
+	b.Music = b.newMusicService(clientVoice{bot: b}, clientVoice{bot: b})
+
+	err = b.parseOptions(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("options parsing failed: %w", err)
+	}
+
+	// The Discord client is created last, as options decide whether it connects with a single gateway or shards.
+	client, err := b.newDiscordClient(Token)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("error creating the bot client"), err)
+	}
 	b.Client = client
 
-	b.Lavalink = disgolink.New(b.Client.ApplicationID(),
-		disgolink.WithListenerFunc(b.onPlayerPause),
-		disgolink.WithListenerFunc(b.onPlayerResume),
+	return b, nil
+}
+
+// newBot returns a Bot with its internal state initialised but without any Discord or Lavalink clients.
+func newBot(logger *logrus.Logger) *Bot {
+	b := &Bot{
+		Queues: &QueueManager{
+			queues: make(map[snowflake.ID]*Queue),
+		},
+		logger:       logger,
+		VersionInfo:  version.String(),
+		Store:        store.NewMemory(),
+		playerEvents: newEventHub(),
+		capabilities: newCapabilityRegistry(),
+		RateLimits:   maps.Clone(defaultRateLimits),
+		rateLimiter:  ratelimit.New(nil),
+		shutdown:     make(chan struct{}),
+		cdnClient:    &http.Client{Timeout: attachmentTimeout},
+	}
+	b.idleTimers = newIdleScheduler(realClock{}, b.idleTimeout, b.onIdleTimeout)
+	b.voiceStatuses = newVoiceStatuses(realClock{}, b.sendVoiceStatus, logger)
+	b.presence = newThrottledUpdates(realClock{}, presenceLimit, "the presence of shard", b.sendPresence, logger)
+	b.Lyrics = lyrics.LavaLyrics{Node: b.bestNode}
+	return b
+}
+
+// newMusicService returns a MusicService playing through the bot's Lavalink client, which reads voice states from
+// states and joins voice channels with voice.
+func (b *Bot) newMusicService(states VoiceStates, voice VoiceConnector) *MusicService {
+	players := lavalinkPlayers{client: b.Lavalink}
+	music := NewMusicService(players, players, states, voice, b.Queues)
+	music.idle = b
+	music.capabilities = b
+	music.policies = b
+	music.listeners = b
+	music.events = b.playerEvents
+	music.SameChannel = true
+	return music
+}
+
+// newDiscordClient creates a Discord client with all event listeners registered. opts are applied last and can
+// replace the gateway or REST client.
+func (b *Bot) newDiscordClient(token string, opts ...bot.ConfigOpt) (bot.Client, error) {
+	return disgo.New(token, append(append(b.gatewayOpts(),
+		bot.WithCacheConfigOpts(
+			cache.WithCaches(cache.FlagVoiceStates, cache.FlagMembers, cache.FlagChannels, cache.FlagStageInstances),
+		),
+		bot.WithEventListenerFunc(b.onApplicationCommand),
+		bot.WithEventListenerFunc(b.onVoiceStateUpdate),
+		bot.WithEventListenerFunc(b.onVoiceServerUpdate),
+		bot.WithEventListenerFunc(b.onGuildReady),
+	), opts...)...)
+}
+
+// newLavalinkClient creates a Lavalink client for userID with all player event listeners registered.
+func (b *Bot) newLavalinkClient(userID snowflake.ID) disgolink.Client {
+	opts := []disgolink.ConfigOpt{
 		disgolink.WithListenerFunc(b.onTrackStart),
 		disgolink.WithListenerFunc(b.onTrackEnd),
 		disgolink.WithListenerFunc(b.onTrackException),
 		disgolink.WithListenerFunc(b.onTrackStuck),
 		disgolink.WithListenerFunc(b.onWebSocketClosed),
 		disgolink.WithListenerFunc(b.onUnknownEvent),
+		disgolink.WithPlugins(capabilityRefresher{bot: b}),
 		disgolink.WithLogger(slog.New(NewLogrusAdapter(b.logger))),
-		disgolink.WithHTTPClient(b.HTTPClient),
-	)
-	err = b.parseOptions(opts...)
-	if err != nil {
-		return nil, fmt.Errorf("options parsing failed: %w", err)
 	}
-
-	return b, nil
+	if b.HTTPClient != nil {
+		opts = append(opts, disgolink.WithHTTPClient(b.HTTPClient))
+	}
+	return disgolink.New(userID, opts...)
 }
 
 func (b *Bot) onApplicationCommand(event *events.ApplicationCommandInteractionCreate) {
@@ -99,38 +174,92 @@ func (b *Bot) onApplicationCommand(event *events.ApplicationCommandInteractionCr
 	handler, ok := b.Handlers[data.CommandName()]
 	if !ok {
 		b.logger.Warnf("unknown command: %s", data.CommandName())
+		metrics.CommandsHandled.WithLabelValues(data.CommandName(), metrics.OutcomeUnknown).Inc()
+		b.respondError(event, trackResponse(event), data.CommandName(), userErrorf("Unknown command `/%s`", data.CommandName()))
 		return
 	}
-	if err := handler(event, data); err != nil {
-		b.logger.Errorf("error handling command %s: %v", data.CommandName(), err)
+	if b.throttle(event, data.CommandName()) {
+		return
+	}
+	response := trackResponse(event)
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout(data.CommandName()))
+	defer cancel()
+	start := time.Now()
+	err := runHandler(ctx, handler, event, data)
+	metrics.CommandDuration.WithLabelValues(data.CommandName()).Observe(time.Since(start).Seconds())
+	if err == nil && response.get() == 0 {
+		err = errNoResponse
+	}
+	metrics.CommandsHandled.WithLabelValues(data.CommandName(), commandOutcome(err)).Inc()
+	if err != nil {
+		b.respondError(event, response, data.CommandName(), err)
 	}
 }
 
+// runHandler runs handler, turning a panic into a panicError so one broken command can't take down the bot.
+func runHandler(ctx context.Context, handler CommandHandler, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &panicError{value: r, stack: debug.Stack()}
+		}
+	}()
+	return handler(ctx, event, data)
+}
+
 func (b *Bot) onVoiceStateUpdate(event *events.GuildVoiceStateUpdate) {
-	if event.VoiceState.UserID != b.Client.ApplicationID() {
-		return
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+	if event.VoiceState.UserID == b.Client.ApplicationID() {
+		previous := b.botChannel(event.VoiceState.GuildID)
+		b.Lavalink.OnVoiceStateUpdate(ctx, event.VoiceState.GuildID, event.VoiceState.ChannelID, event.VoiceState.SessionID)
+		// Only leaving voice tears the session down, moving to another channel keeps the player and queue.
+		if event.VoiceState.ChannelID == nil {
+			b.Queues.Delete(event.VoiceState.GuildID)
+			if previous != nil {
+				b.clearVoiceStatus(*previous)
+			}
+			b.updatePresence()
+			b.clearIdle(event.VoiceState.GuildID)
+			b.Music.forgetAutoPause(event.VoiceState.GuildID)
+			b.rejoinAlwaysOn(event.VoiceState.GuildID)
+			return
+		}
+		if previous != nil && *previous != *event.VoiceState.ChannelID {
+			b.onBotMoved(event.VoiceState.GuildID, *previous, *event.VoiceState.ChannelID)
+		}
+		b.joinStage(event.VoiceState)
 	}
-	b.Lavalink.OnVoiceStateUpdate(context.TODO(), event.VoiceState.GuildID, event.VoiceState.ChannelID, event.VoiceState.SessionID)
-	if event.VoiceState.ChannelID == nil {
-		b.Queues.Delete(event.VoiceState.GuildID)
+	if event.Member.User.ID != 0 && event.Member.GuildID == 0 {
+		// Members in voice state updates don't carry the guild ID, so disgo caches them under the wrong guild.
+		member := event.Member
+		member.GuildID = event.VoiceState.GuildID
+		b.Client.Caches().AddMember(member)
 	}
+	b.checkListeners(ctx, event.VoiceState.GuildID)
 }
 
 func (b *Bot) onVoiceServerUpdate(event *events.VoiceServerUpdate) {
-	b.Lavalink.OnVoiceServerUpdate(context.TODO(), event.GuildID, event.Token, *event.Endpoint)
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+	b.Lavalink.OnVoiceServerUpdate(ctx, event.GuildID, event.Token, *event.Endpoint)
 }
 
 func (b *Bot) Shutdown() {
 	b.logger.Infof("shutting down...")
-	for _, queue := range b.Queues.queues {
+	b.Queues.ForEach(func(_ snowflake.ID, queue *Queue) {
 		queue.Clear()
-	}
+	})
 	close(b.shutdown)
 	b.logger.Debugf("queues cleared")
-	b.Lavalink.Close()
-	b.logger.Debugf("lavalink connection closed")
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	b.leaveIdleGuilds(ctx)
+	b.voiceStatuses.stop()
+	b.presence.stop()
+	b.Lavalink.Close()
+	b.logger.Debugf("lavalink connection closed")
+	b.stopHTTPServer(ctx)
+	b.stopAPIServer(ctx)
 	b.Client.Close(ctx)
 	b.logger.Debugf("Bot shutdown complete")
 }
@@ -150,43 +279,3 @@ func (b *Bot) parseOptions(opts ...Option) error {
 
 	return nil
 }
-
-func (b *Bot) IdleTimeoutCleaner() {
-	b.logger.Infof("starting idle timeout cleaner with interval %s", b.IdleTimeout)
-	ticker := time.NewTicker(b.IdleTimeout / 10) // Check every 10% of the idle timeout duration
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			for guildID, idleTime := range b.idleTimes {
-				if now.Sub(idleTime) >= b.IdleTimeout {
-					b.logger.Infof("removing idle timeout for guild %s", guildID)
-					delete(b.idleTimes, guildID)
-					err := b.Client.UpdateVoiceState(context.Background(), guildID, nil, false, false)
-					if err != nil {
-						b.logger.Errorf("error updating voice state for guild %s: %v", guildID, err)
-					} else {
-						b.logger.Infof("disconnected from voice channel for guild %s due to idle timeout", guildID)
-					}
-				}
-			}
-		case <-b.shutdown:
-			b.logger.Infof("idle timeout cleaner shutting down")
-
-			ticker.Stop()
-			for guildID := range b.idleTimes {
-				b.logger.Infof("removing remaining idle timeout for guild %s", guildID)
-				delete(b.idleTimes, guildID)
-				err := b.Client.UpdateVoiceState(context.TODO(), guildID, nil, false, false)
-				if err != nil {
-					b.logger.Errorf("error updating voice state for guild %s: %v", guildID, err)
-				} else {
-					b.logger.Infof("disconnected from voice channel for guild %s due to shutdown", guildID)
-				}
-			}
-			return
-		}
-	}
-}
@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// searchSources maps search prefixes to the source manager which handles them. Prefixes not listed here are
+// passed to Lavalink unchecked.
+var searchSources = map[string]string{
+	string(lavalink.SearchTypeYouTube):      "youtube",
+	string(lavalink.SearchTypeYouTubeMusic): "youtube",
+	string(lavalink.SearchTypeSoundCloud):   "soundcloud",
+	"dzsearch":                              "deezer",
+	"dzisrc":                                "deezer",
+	"spsearch":                              "spotify",
+	"amsearch":                              "applemusic",
+}
+
+// Capabilities is what a Lavalink node reported it supports in /v4/info.
+type Capabilities struct {
+	Version        string
+	SourceManagers []string
+	Filters        []string
+	Plugins        []string
+}
+
+func capabilitiesFromInfo(info lavalink.Info) Capabilities {
+	capabilities := Capabilities{
+		Version:        info.Version.Semver,
+		SourceManagers: info.SourceManagers,
+		Filters:        info.Filters,
+	}
+	for _, plugin := range info.Plugins {
+		capabilities.Plugins = append(capabilities.Plugins, plugin.Name)
+	}
+	return capabilities
+}
+
+// SupportsSearch reports whether the search prefix of identifier, if any, is handled by one of the node's
+// source managers.
+func (c Capabilities) SupportsSearch(identifier string) bool {
+	prefix, _, found := strings.Cut(identifier, ":")
+	if !found {
+		return true
+	}
+	source, ok := searchSources[prefix]
+	return !ok || slices.Contains(c.SourceManagers, source)
+}
+
+// UnsupportedFilters returns the names of the filters set in filters which the node does not support. Plugin
+// filters are not checked.
+func (c Capabilities) UnsupportedFilters(filters lavalink.Filters) []string {
+	filters.PluginFilters = nil
+	raw, err := json.Marshal(filters)
+	if err != nil {
+		return nil
+	}
+	var set map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &set); err != nil {
+		return nil
+	}
+	var unsupported []string
+	for name := range set {
+		if !slices.Contains(c.Filters, name) {
+			unsupported = append(unsupported, name)
+		}
+	}
+	slices.Sort(unsupported)
+	return unsupported
+}
+
+// capabilityLookup reports what the Lavalink nodes support. ok is false when it is not known, in which case
+// everything is assumed to be supported.
+type capabilityLookup interface {
+	// loaderCapabilities returns the capabilities of the node used to load tracks.
+	loaderCapabilities() (Capabilities, bool)
+	// playerCapabilities returns the capabilities of the node hosting the guild's player.
+	playerCapabilities(guildID snowflake.ID) (Capabilities, bool)
+}
+
+type unknownCapabilities struct{}
+
+func (unknownCapabilities) loaderCapabilities() (Capabilities, bool) { return Capabilities{}, false }
+func (unknownCapabilities) playerCapabilities(snowflake.ID) (Capabilities, bool) {
+	return Capabilities{}, false
+}
+
+// capabilityRegistry holds the capabilities of each node, keyed by node name.
+type capabilityRegistry struct {
+	mu    sync.RWMutex
+	nodes map[string]Capabilities
+}
+
+func newCapabilityRegistry() *capabilityRegistry {
+	return &capabilityRegistry{nodes: make(map[string]Capabilities)}
+}
+
+func (r *capabilityRegistry) set(node string, capabilities Capabilities) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes[node] = capabilities
+}
+
+func (r *capabilityRegistry) get(node string) (Capabilities, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	capabilities, ok := r.nodes[node]
+	return capabilities, ok
+}
+
+func (r *capabilityRegistry) all() map[string]Capabilities {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	nodes := make(map[string]Capabilities, len(r.nodes))
+	for name, capabilities := range r.nodes {
+		nodes[name] = capabilities
+	}
+	return nodes
+}
+
+// addNode adds a Lavalink node. Its capabilities are recorded by capabilityRefresher once it is connected.
+func (b *Bot) addNode(ctx context.Context, config disgolink.NodeConfig) (disgolink.Node, error) {
+	return b.Lavalink.AddNode(ctx, config)
+}
+
+// refreshCapabilities queries the node's /v4/info and records the result.
+func (b *Bot) refreshCapabilities(ctx context.Context, node disgolink.Node) (Capabilities, error) {
+	info, err := node.Info(ctx)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("error fetching node info: %w", err)
+	}
+	capabilities := capabilitiesFromInfo(*info)
+	b.capabilities.set(node.Config().Name, capabilities)
+	b.logger.Debugf("Lavalink node %s supports sources %v, filters %v and plugins %v", node.Config().Name, capabilities.SourceManagers, capabilities.Filters, capabilities.Plugins)
+	return capabilities, nil
+}
+
+// capabilityRefresher is a disgolink plugin which refreshes the capabilities of a node whenever it connects, so a
+// node which was unreachable when it was added or was upgraded since is not judged by stale capabilities.
+type capabilityRefresher struct {
+	bot *Bot
+}
+
+func (capabilityRefresher) Name() string    { return "capabilities" }
+func (capabilityRefresher) Version() string { return "1.0.0" }
+
+// OnNodeOpen runs before AddNode returns and on every reconnect, so the capabilities are known as soon as the node
+// can be used.
+func (r capabilityRefresher) OnNodeOpen(node disgolink.Node) {
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+	if _, err := r.bot.refreshCapabilities(ctx, node); err != nil {
+		r.bot.logger.Warnf("error fetching capabilities of lavalink node %s, assuming it supports everything: %v", node.Config().Name, err)
+	}
+}
+
+func (capabilityRefresher) OnNodeClose(disgolink.Node)             {}
+func (capabilityRefresher) OnNodeMessageIn(disgolink.Node, []byte) {}
+func (capabilityRefresher) OnNewPlayer(disgolink.Player)           {}
+func (capabilityRefresher) OnDestroyPlayer(disgolink.Player)       {}
+
+func (b *Bot) nodeCapabilities(node disgolink.Node) (Capabilities, bool) {
+	if node == nil {
+		return Capabilities{}, false
+	}
+	return b.capabilities.get(node.Config().Name)
+}
+
+func (b *Bot) loaderCapabilities() (Capabilities, bool) {
+	return b.nodeCapabilities(b.bestNode())
+}
+
+func (b *Bot) playerCapabilities(guildID snowflake.ID) (Capabilities, bool) {
+	if b.Lavalink == nil {
+		return Capabilities{}, false
+	}
+	player := b.Lavalink.ExistingPlayer(guildID)
+	if player == nil {
+		return b.loaderCapabilities()
+	}
+	return b.nodeCapabilities(player.Node())
+}
+
+// availableCommands returns the commands to register, with the play command's search sources limited to those
+// supported by at least one node. All sources are kept while no node's capabilities are known.
+func (b *Bot) availableCommands() []discord.ApplicationCommandCreate {
+	nodes := b.capabilities.all()
+	if len(nodes) == 0 {
+		return commands
+	}
+	supported := func(source string) bool {
+		for _, capabilities := range nodes {
+			if capabilities.SupportsSearch(source + ":") {
+				return true
+			}
+		}
+		return false
+	}
+
+	available := slices.Clone(commands)
+	for i, command := range available {
+		slash, ok := command.(discord.SlashCommandCreate)
+		if !ok || slash.Name != "play" {
+			continue
+		}
+		slash.Options = slices.Clone(slash.Options)
+		for j, option := range slash.Options {
+			sourceOption, ok := option.(discord.ApplicationCommandOptionString)
+			if !ok || sourceOption.Name != "source" {
+				continue
+			}
+			sourceOption.Choices = slices.DeleteFunc(slices.Clone(sourceOption.Choices), func(choice discord.ApplicationCommandOptionChoiceString) bool {
+				return !supported(choice.Value)
+			})
+			slash.Options[j] = sourceOption
+		}
+		available[i] = slash
+	}
+	return available
+}
+
+// capabilitiesString renders a node's capabilities for /debug.
+func capabilitiesString(capabilities Capabilities) string {
+	list := func(values []string) string {
+		if len(values) == 0 {
+			return "none"
+		}
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprintf("Sources: %s\nFilters: %s\nPlugins: %s", list(capabilities.SourceManagers), list(capabilities.Filters), list(capabilities.Plugins))
+}
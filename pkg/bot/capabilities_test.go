@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Capabilities_SupportsSearch(t *testing.T) {
+	capabilities := Capabilities{SourceManagers: []string{"youtube", "soundcloud"}}
+
+	assert.True(t, capabilities.SupportsSearch("ytmsearch:song"))
+	assert.True(t, capabilities.SupportsSearch("scsearch:song"))
+	assert.True(t, capabilities.SupportsSearch("https://example.com/a"), "URLs are not checked")
+	assert.True(t, capabilities.SupportsSearch("plain query"))
+	assert.False(t, capabilities.SupportsSearch("dzisrc:USUM71703861"))
+	assert.False(t, capabilities.SupportsSearch("spsearch:song"))
+}
+
+func Test_Capabilities_UnsupportedFilters(t *testing.T) {
+	capabilities := Capabilities{Filters: []string{"equalizer"}}
+
+	assert.Empty(t, capabilities.UnsupportedFilters(lavalink.Filters{Equalizer: bassBoost}))
+	assert.Equal(t, []string{"karaoke", "timescale"}, capabilities.UnsupportedFilters(lavalink.Filters{
+		Equalizer:     bassBoost,
+		Timescale:     &lavalink.Timescale{Speed: 1.2},
+		Karaoke:       &lavalink.Karaoke{},
+		PluginFilters: map[string]any{"echo": map[string]any{}},
+	}))
+}
+
+func Test_AvailableCommandsHidesUnsupportedSources(t *testing.T) {
+	b := newBot(nil)
+	assert.Equal(t, commands, b.availableCommands(), "all sources are kept while capabilities are unknown")
+
+	b.capabilities.set("a", Capabilities{SourceManagers: []string{"youtube"}})
+	b.capabilities.set("b", Capabilities{SourceManagers: []string{"deezer"}})
+
+	var sources []string
+	for _, command := range b.availableCommands() {
+		if command.CommandName() != "play" {
+			continue
+		}
+		for _, option := range command.(discord.SlashCommandCreate).Options {
+			if option.OptionName() != "source" {
+				continue
+			}
+			for _, choice := range option.(discord.ApplicationCommandOptionString).Choices {
+				sources = append(sources, choice.Value)
+			}
+		}
+	}
+	assert.Equal(t, []string{"ytsearch", "ytmsearch", "dzsearch", "dzisrc"}, sources)
+	assert.Len(t, commands[0].(discord.SlashCommandCreate).Options[1].(discord.ApplicationCommandOptionString).Choices, 7, "the registered commands are not modified")
+}
+
+func Test_Integration_CapabilitiesRecordedOnConnect(t *testing.T) {
+	f := newIntegrationFixture(t)
+
+	capabilities, ok := f.bot.capabilities.get("test")
+
+	require.True(t, ok)
+	assert.Equal(t, []string{"youtube", "soundcloud", "http"}, capabilities.SourceManagers)
+	assert.Equal(t, []string{"equalizer", "timescale", "volume"}, capabilities.Filters)
+}
+
+func Test_Integration_CapabilitiesRefreshedOnReconnect(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.server.SetInfo(lavalink.Info{SourceManagers: []string{"deezer"}, Filters: []string{"volume"}})
+
+	f.server.DropConnection()
+
+	assert.Eventually(t, func() bool {
+		capabilities, ok := f.bot.capabilities.get("test")
+		return ok && slices.Equal(capabilities.SourceManagers, []string{"deezer"})
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func Test_Integration_UnsupportedFilterRejected(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.server.AddTrack("https://example.com/a", testTrack("a"))
+	ctx := context.Background()
+	_, err := f.bot.Music.Play(ctx, testGuildID, testChannelID, testUserID, "https://example.com/a", "")
+	require.NoError(t, err)
+
+	f.server.SetInfo(lavalink.Info{SourceManagers: []string{"http"}, Filters: []string{"volume"}})
+	_, err = f.bot.refreshCapabilities(ctx, f.bot.bestNode())
+	require.NoError(t, err)
+
+	err = f.bot.Music.SetBassBoost(ctx, testGuildID, true)
+	assert.ErrorIs(t, err, ErrUnsupportedFilter)
+	assert.ErrorContains(t, err, "equalizer")
+	_, err = f.bot.Music.Play(ctx, testGuildID, testChannelID, testUserID, "song", "")
+	assert.ErrorIs(t, err, ErrUnsupportedSource)
+}
+
+func Test_Gateway_PlayUnsupportedSource(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	f.replay("play_unsupported_source")
+
+	f.discord.AssertGolden("play_unsupported_source")
+	assert.Empty(t, f.server.Updates())
+}
@@ -1,14 +1,58 @@
 package bot
 
 import (
+	"go-discord-music/pkg/queuefile"
+
 	"github.com/Cyb3r-Jak3/common/v5"
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/handler"
+	"github.com/disgoorg/json"
 	"github.com/disgoorg/snowflake/v2"
 
 	"github.com/disgoorg/disgolink/v3/lavalink"
 )
 
+var (
+	playlistNameOption = discord.ApplicationCommandOptionString{
+		Name:        "name",
+		Description: "The name of the playlist",
+		Required:    true,
+		MaxLength:   common.Ptr(100),
+	}
+	blocklistEntryOption = discord.ApplicationCommandOptionString{
+		Name:        "entry",
+		Description: "The keyword or URL",
+		Required:    true,
+		MaxLength:   common.Ptr(100),
+	}
+	rateLimitCommandOption = discord.ApplicationCommandOptionString{
+		Name:        "command",
+		Description: "The command name like play, or * for all commands without their own limit",
+		Required:    true,
+		MaxLength:   common.Ptr(32),
+	}
+	seekAmountOption = discord.ApplicationCommandOptionString{
+		Name:        "amount",
+		Description: "How far to move, like 30s or 1:00, 10 seconds by default",
+		Required:    false,
+	}
+	playlistScopeOption = discord.ApplicationCommandOptionString{
+		Name:        "scope",
+		Description: "Whether to use your personal playlists or the ones shared with the server",
+		Required:    false,
+		Choices: []discord.ApplicationCommandOptionChoiceString{
+			{
+				Name:  "Personal",
+				Value: string(playlistScopePersonal),
+			},
+			{
+				Name:  "Server",
+				Value: string(playlistScopeServer),
+			},
+		},
+	}
+)
+
 var commands = []discord.ApplicationCommandCreate{
 	discord.SlashCommandCreate{
 		Name:        "play",
@@ -115,14 +159,14 @@ var commands = []discord.ApplicationCommandCreate{
 		Name:        "seek",
 		Description: "Seeks to a specific position in the current song",
 		Options: []discord.ApplicationCommandOption{
-			discord.ApplicationCommandOptionInt{
+			discord.ApplicationCommandOptionString{
 				Name:        "position",
-				Description: "The position to seek to",
+				Description: "A timestamp like 1:23 or 90s, or an offset from the current position like +30s or -1m",
 				Required:    true,
 			},
 			discord.ApplicationCommandOptionInt{
 				Name:        "unit",
-				Description: "The unit of the position",
+				Description: "The unit of a position given as a plain number, seconds by default",
 				Required:    false,
 				Choices: []discord.ApplicationCommandOptionChoiceInt{
 					{
@@ -145,6 +189,20 @@ var commands = []discord.ApplicationCommandCreate{
 			},
 		},
 	},
+	discord.SlashCommandCreate{
+		Name:        "forward",
+		Description: "Skips forward in the current song",
+		Options: []discord.ApplicationCommandOption{
+			seekAmountOption,
+		},
+	},
+	discord.SlashCommandCreate{
+		Name:        "rewind",
+		Description: "Rewinds the current song",
+		Options: []discord.ApplicationCommandOption{
+			seekAmountOption,
+		},
+	},
 	discord.SlashCommandCreate{
 		Name:        "shuffle",
 		Description: "Shuffles the current queue",
@@ -153,6 +211,235 @@ var commands = []discord.ApplicationCommandCreate{
 		Name:        "connect",
 		Description: "Forces the bot to connect to a voice channel",
 	},
+	discord.SlashCommandCreate{
+		Name:        "move-here",
+		Description: "Moves the bot to your voice channel without interrupting playback",
+	},
+	discord.SlashCommandCreate{
+		Name:        "24-7",
+		Description: "Keeps the bot connected to a voice channel around the clock",
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionBool{
+				Name:        "enabled",
+				Description: "Whether 24/7 mode should be enabled or disabled",
+				Required:    true,
+			},
+			discord.ApplicationCommandOptionChannel{
+				Name:         "channel",
+				Description:  "The voice channel to stay in, defaults to your current channel",
+				Required:     false,
+				ChannelTypes: []discord.ChannelType{discord.ChannelTypeGuildVoice, discord.ChannelTypeGuildStageVoice},
+			},
+			discord.ApplicationCommandOptionString{
+				Name:        "fallback",
+				Description: "A playlist or radio stream to play whenever the queue is empty",
+				Required:    false,
+			},
+		},
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+	},
+	discord.SlashCommandCreate{
+		Name:        "queue-policy",
+		Description: "Limits what members can add to the queue",
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "show",
+				Description: "Shows the current queue limits",
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "set",
+				Description: "Changes the queue limits, options which are left out keep their value",
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionInt{
+						Name:        "max-queue-length",
+						Description: "The maximum number of tracks in the queue, 0 for no limit",
+						Required:    false,
+						MinValue:    common.Ptr(0),
+					},
+					discord.ApplicationCommandOptionInt{
+						Name:        "max-tracks-per-user",
+						Description: "The maximum number of tracks each member can have queued, 0 for no limit",
+						Required:    false,
+						MinValue:    common.Ptr(0),
+					},
+					discord.ApplicationCommandOptionString{
+						Name:        "max-track-length",
+						Description: "The maximum length of a track like 10m or 1:30:00, 0 for no limit",
+						Required:    false,
+					},
+					discord.ApplicationCommandOptionBool{
+						Name:        "streams",
+						Description: "Whether live streams can be queued",
+						Required:    false,
+					},
+					discord.ApplicationCommandOptionString{
+						Name:        "sources",
+						Description: "Comma separated sources tracks can come from like youtube,deezer, or all",
+						Required:    false,
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "block",
+				Description: "Rejects tracks whose title, artist or link contains a keyword or URL",
+				Options: []discord.ApplicationCommandOption{
+					blocklistEntryOption,
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "unblock",
+				Description: "Removes a keyword or URL from the blocklist",
+				Options: []discord.ApplicationCommandOption{
+					blocklistEntryOption,
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "reset",
+				Description: "Removes all queue limits and the blocklist",
+			},
+		},
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+	},
+	discord.SlashCommandCreate{
+		Name:        "rate-limit",
+		Description: "Limits how often each member can use commands",
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "show",
+				Description: "Shows the command rate limits",
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "set",
+				Description: "Changes the rate limit of a command",
+				Options: []discord.ApplicationCommandOption{
+					rateLimitCommandOption,
+					discord.ApplicationCommandOptionString{
+						Name:        "limit",
+						Description: "Uses per period like 3/10s, or off for no limit",
+						Required:    true,
+						MaxLength:   common.Ptr(20),
+					},
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "reset",
+				Description: "Restores the default rate limit of a command, or of all commands",
+				Options: []discord.ApplicationCommandOption{
+					discord.ApplicationCommandOptionString{
+						Name:        rateLimitCommandOption.Name,
+						Description: rateLimitCommandOption.Description,
+						Required:    false,
+						MaxLength:   rateLimitCommandOption.MaxLength,
+					},
+				},
+			},
+		},
+		DefaultMemberPermissions: json.NewNullablePtr(discord.PermissionManageGuild),
+	},
+	discord.SlashCommandCreate{
+		Name:        "lyrics",
+		Description: "Shows the lyrics of the current song",
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionInt{
+				Name:        "page",
+				Description: "The page to show for long lyrics",
+				Required:    false,
+				MinValue:    common.Ptr(1),
+			},
+			discord.ApplicationCommandOptionBool{
+				Name:        "synced",
+				Description: "Show synced lyrics which follow the song, if available",
+				Required:    false,
+			},
+		},
+	},
+	discord.SlashCommandCreate{
+		Name:        "queue-export",
+		Description: "Exports the current queue as a file",
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionString{
+				Name:        "format",
+				Description: "The file format, JSON keeps the tracks for this bot while M3U works with other players",
+				Required:    false,
+				Choices: []discord.ApplicationCommandOptionChoiceString{
+					{
+						Name:  "JSON",
+						Value: string(queuefile.FormatJSON),
+					},
+					{
+						Name:  "M3U",
+						Value: string(queuefile.FormatM3U),
+					},
+				},
+			},
+		},
+	},
+	discord.SlashCommandCreate{
+		Name:        "queue-import",
+		Description: "Plays the tracks of an exported queue file",
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionAttachment{
+				Name:        "file",
+				Description: "A JSON or M3U queue file",
+				Required:    true,
+			},
+		},
+	},
+	discord.SlashCommandCreate{
+		Name:        "playlist",
+		Description: "Manages saved playlists",
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "save",
+				Description: "Saves the current queue or the given tracks as a playlist",
+				Options: []discord.ApplicationCommandOption{
+					playlistNameOption,
+					discord.ApplicationCommandOptionString{
+						Name:        "identifier",
+						Description: "A song link, playlist or search query to save instead of the current queue",
+						Required:    false,
+					},
+					playlistScopeOption,
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "append",
+				Description: "Adds the current queue or the given tracks to a playlist",
+				Options: []discord.ApplicationCommandOption{
+					playlistNameOption,
+					discord.ApplicationCommandOptionString{
+						Name:        "identifier",
+						Description: "A song link, playlist or search query to add instead of the current queue",
+						Required:    false,
+					},
+					playlistScopeOption,
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "load",
+				Description: "Plays a saved playlist",
+				Options: []discord.ApplicationCommandOption{
+					playlistNameOption,
+					playlistScopeOption,
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "list",
+				Description: "Lists saved playlists",
+				Options: []discord.ApplicationCommandOption{
+					playlistScopeOption,
+				},
+			},
+			discord.ApplicationCommandOptionSubCommand{
+				Name:        "delete",
+				Description: "Deletes a saved playlist",
+				Options: []discord.ApplicationCommandOption{
+					playlistNameOption,
+					playlistScopeOption,
+				},
+			},
+		},
+	},
 	discord.SlashCommandCreate{
 		Name:        "debug",
 		Description: "Debug command to get information about the bot",
@@ -164,7 +451,7 @@ var commands = []discord.ApplicationCommandCreate{
 }
 
 func (b *Bot) registerCommands() {
-	if err := handler.SyncCommands(b.Client, commands, []snowflake.ID{}); err != nil {
+	if err := handler.SyncCommands(b.Client, b.availableCommands(), []snowflake.ID{}); err != nil {
 		b.logger.Fatalf("error while registering commands: %v", err)
 	}
 }
@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"go-discord-music/pkg/lyrics"
+	"go-discord-music/pkg/metrics"
+	"go-discord-music/pkg/policy"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/sirupsen/logrus"
+)
+
+// UserError is a failure caused by the user, such as invalid input or using a command without a player. Its
+// message is shown to the user as is. Any other error returned by a command handler is internal: it is logged and the
+// user only sees a correlation ID to report.
+type UserError struct {
+	Message string
+	// Err is the cause, if any. It is logged but not shown.
+	Err error
+}
+
+func (e *UserError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
+
+// userErrorf returns a UserError with a formatted message.
+func userErrorf(format string, args ...any) error {
+	return &UserError{Message: fmt.Sprintf(format, args...)}
+}
+
+// userMessage returns the message shown to the user for errors they caused or can act on. ok is false for
+// internal errors.
+func userMessage(err error) (message string, ok bool) {
+	var (
+		userErr     *UserError
+		rejectedErr *policy.RejectedError
+	)
+	switch {
+	case errors.As(err, &userErr):
+		return userErr.Message, true
+	case errors.Is(err, ErrNoPlayer):
+		return "No player found", true
+	case errors.Is(err, ErrNoTrack):
+		return "No track found", true
+	case errors.Is(err, ErrNoTracks):
+		return "No tracks in queue", true
+	case errors.Is(err, ErrNotInVoice):
+		return "You need to be in a voice channel to use this command", true
+	case errors.Is(err, ErrNotInBotChannel):
+		return "You need to be in the bot's voice channel to use this command", true
+	case errors.Is(err, ErrChannelBusy):
+		return "The bot is playing for others in another voice channel, join them or wait until they are done", true
+	case errors.Is(err, ErrAlreadyInChannel):
+		return "The bot is already in your voice channel", true
+	case errors.Is(err, ErrAlreadyConnected):
+		return "Player already connected", true
+	case errors.Is(err, ErrNoNode):
+		return "No Lavalink node is available right now, try again later", true
+	case errors.Is(err, ErrInvalidQueueType):
+		return "Unknown queue type", true
+	case errors.Is(err, ErrQueueIndexInvalid):
+		return "That position is not in the queue", true
+	case errors.Is(err, ErrUnsupportedSource):
+		return "That search source is not available on the Lavalink node, try another `source`", true
+	case errors.Is(err, ErrUnsupportedFilter):
+		return "That filter is not available on the Lavalink node", true
+	case errors.Is(err, ErrNotSeekable):
+		return "Live streams can't be seeked", true
+	case errors.Is(err, ErrSeekOutOfRange):
+		return "That position is outside the current track", true
+	case errors.Is(err, lyrics.ErrUnsupported):
+		return "Lyrics are not available on this bot", true
+	case errors.As(err, &rejectedErr):
+		return "Nothing was queued:" + rejectionList(rejectedErr.Rejections), true
+	default:
+		return "", false
+	}
+}
+
+// serviceError classifies an error returned while performing action. Errors the user can act on are returned as a
+// UserError, anything else is wrapped as an internal error.
+func serviceError(action string, err error) error {
+	if message, ok := userMessage(err); ok {
+		return &UserError{Message: message, Err: err}
+	}
+	return fmt.Errorf("error while %s: %w", action, err)
+}
+
+// interactionResponse records how a command interaction was responded to, so a failure can always be reported.
+type interactionResponse struct {
+	mu           sync.Mutex
+	responseType discord.InteractionResponseType
+}
+
+// trackResponse wraps the responder of event to record its responses.
+func trackResponse(event *events.ApplicationCommandInteractionCreate) *interactionResponse {
+	r := &interactionResponse{}
+	respond := event.Respond
+	event.Respond = func(responseType discord.InteractionResponseType, data discord.InteractionResponseData, opts ...rest.RequestOpt) error {
+		err := respond(responseType, data, opts...)
+		if err == nil {
+			r.mu.Lock()
+			r.responseType = responseType
+			r.mu.Unlock()
+		}
+		return err
+	}
+	return r
+}
+
+// get returns the type of the response sent, 0 when the interaction has not been responded to.
+func (r *interactionResponse) get() discord.InteractionResponseType {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.responseType
+}
+
+// errNoResponse is reported when a handler returns without responding to the interaction.
+var errNoResponse = errors.New("command handler did not respond")
+
+// panicError is a recovered panic of a command handler.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v\n%s", e.value, e.stack)
+}
+
+// commandOutcome returns the metrics outcome of a command which returned err.
+func commandOutcome(err error) string {
+	var panicErr *panicError
+	if err == nil {
+		return metrics.OutcomeSuccess
+	}
+	if errors.As(err, &panicErr) {
+		return metrics.OutcomePanic
+	}
+	if _, ok := userMessage(err); ok {
+		return metrics.OutcomeUserError
+	}
+	return metrics.OutcomeError
+}
+
+// respondError reports err to the user of a command. Messages of user errors are shown as is, internal errors are
+// logged with the interaction ID as correlation ID, which is the only detail shown to the user. The reply is ephemeral
+// unless the response was already deferred publicly, in which case the deferred response is replaced.
+func (b *Bot) respondError(event *events.ApplicationCommandInteractionCreate, response *interactionResponse, command string, err error) {
+	correlationID := event.ID().String()
+	logger := b.logger.WithFields(logrus.Fields{
+		"command":        command,
+		"correlation_id": correlationID,
+		"user_id":        event.User().ID.String(),
+	})
+	if event.GuildID() != nil {
+		logger = logger.WithField("guild_id", event.GuildID().String())
+	}
+
+	message, ok := userMessage(err)
+	if ok {
+		logger.Debugf("command failed: %v", err)
+	} else {
+		logger.Errorf("error handling command %s: %v", command, err)
+		message = fmt.Sprintf("Something went wrong while handling `/%s`. If this keeps happening, report reference `%s`", command, correlationID)
+	}
+
+	var sendErr error
+	switch response.get() {
+	case 0:
+		sendErr = event.CreateMessage(discord.MessageCreate{
+			Content: message,
+			Flags:   discord.MessageFlagEphemeral,
+		})
+	case discord.InteractionResponseTypeDeferredCreateMessage:
+		_, sendErr = event.Client().Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content: &message,
+		})
+	default:
+		_, sendErr = event.Client().Rest().CreateFollowupMessage(event.ApplicationID(), event.Token(), discord.MessageCreate{
+			Content: message,
+			Flags:   discord.MessageFlagEphemeral,
+		})
+	}
+	if sendErr != nil {
+		logger.Errorf("error sending error response for command %s: %v", command, sendErr)
+	}
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/policy"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Gateway_InternalErrorShowsCorrelationID(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.Handlers["pause"] = func(context.Context, *events.ApplicationCommandInteractionCreate, discord.SlashCommandInteractionData) error {
+		return errors.New("lavalink exploded")
+	}
+
+	f.replay("pause_without_player")
+
+	f.discord.AssertGolden("internal_error")
+}
+
+func Test_Gateway_ErrorAfterDeferUpdatesResponse(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.Handlers["pause"] = func(_ context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+		if err := event.DeferCreateMessage(false); err != nil {
+			return err
+		}
+		return serviceError("pausing", ErrNoPlayer)
+	}
+
+	f.replay("pause_without_player")
+
+	f.discord.AssertGolden("deferred_error")
+}
+
+func Test_Gateway_MissingResponseIsReported(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.Handlers["pause"] = func(context.Context, *events.ApplicationCommandInteractionCreate, discord.SlashCommandInteractionData) error {
+		return nil
+	}
+
+	f.replay("pause_without_player")
+
+	f.discord.AssertGolden("missing_response")
+}
+
+func Test_Gateway_PanicIsRecovered(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.Handlers["pause"] = func(context.Context, *events.ApplicationCommandInteractionCreate, discord.SlashCommandInteractionData) error {
+		var track *lavalink.Track
+		_ = track.Info.Title
+		return nil
+	}
+
+	f.replay("pause_without_player")
+	f.replay("pause_without_player")
+
+	f.discord.AssertGolden("panic_recovered")
+}
+
+func Test_Gateway_HandlerContextHasDeadline(t *testing.T) {
+	f := newGatewayFixture(t)
+	deadlines := make(map[string]time.Time)
+	recordDeadline := func(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "the context of /%s has no deadline", data.CommandName())
+		deadlines[data.CommandName()] = deadline
+		return event.CreateMessage(discord.MessageCreate{Content: "ok"})
+	}
+	f.bot.Handlers["pause"] = recordDeadline
+	f.bot.Handlers["queue-import"] = recordDeadline
+
+	f.replay("pause_without_player")
+	f.replay("queue_import")
+
+	assert.WithinDuration(t, time.Now().Add(defaultCommandTimeout), deadlines["pause"], time.Second)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), deadlines["queue-import"], time.Second)
+}
+
+func Test_ServiceError(t *testing.T) {
+	var userErr *UserError
+
+	err := serviceError("pausing", fmt.Errorf("wrapped: %w", ErrNoPlayer))
+	assert.ErrorAs(t, err, &userErr)
+	assert.Equal(t, "No player found", userErr.Message)
+	assert.ErrorIs(t, err, ErrNoPlayer)
+
+	err = serviceError("pausing", errors.New("connection reset"))
+	assert.False(t, errors.As(err, &userErr))
+	assert.EqualError(t, err, "error while pausing: connection reset")
+
+	message, ok := userMessage(&policy.RejectedError{})
+	assert.True(t, ok)
+	assert.Equal(t, "Nothing was queued:", message)
+}
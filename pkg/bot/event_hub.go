@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// PlayerEventType identifies the kind of PlayerEvent.
+type PlayerEventType string
+
+const (
+	PlayerEventPause           PlayerEventType = "pause"
+	PlayerEventResume          PlayerEventType = "resume"
+	PlayerEventTrackStart      PlayerEventType = "track_start"
+	PlayerEventTrackEnd        PlayerEventType = "track_end"
+	PlayerEventTrackException  PlayerEventType = "track_exception"
+	PlayerEventTrackStuck      PlayerEventType = "track_stuck"
+	PlayerEventWebSocketClosed PlayerEventType = "websocket_closed"
+	PlayerEventMoved           PlayerEventType = "moved"
+)
+
+// PlayerEvent is a player event sourced from the Lavalink listeners and the MusicService, streamed to API subscribers.
+type PlayerEvent struct {
+	Type    PlayerEventType `json:"type"`
+	GuildID snowflake.ID    `json:"guild_id"`
+	Track   *lavalink.Track `json:"track,omitempty"`
+	Reason  string          `json:"reason,omitempty"`
+	// ChannelID is the voice channel the bot was moved to, set for moved events.
+	ChannelID *snowflake.ID `json:"channel_id,omitempty"`
+	Time      time.Time     `json:"time"`
+}
+
+// eventSubscriberBuffer is the number of events buffered per subscriber before events are dropped.
+const eventSubscriberBuffer = 32
+
+// eventHub fans out player events to subscribers. Slow subscribers miss events instead of blocking the Lavalink listeners.
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[chan PlayerEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscribers: make(map[chan PlayerEvent]struct{}),
+	}
+}
+
+// Subscribe returns a channel receiving published events and a function to cancel the subscription.
+func (h *eventHub) Subscribe() (<-chan PlayerEvent, func()) {
+	ch := make(chan PlayerEvent, eventSubscriberBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends event to every subscriber without blocking.
+func (h *eventHub) Publish(event PlayerEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_EventHub_Publish_DeliversToSubscribers(t *testing.T) {
+	hub := newEventHub()
+	first, cancelFirst := hub.Subscribe()
+	defer cancelFirst()
+	second, cancelSecond := hub.Subscribe()
+	defer cancelSecond()
+
+	hub.Publish(PlayerEvent{Type: PlayerEventPause, GuildID: snowflake.ID(1)})
+
+	event := <-first
+	assert.Equal(t, PlayerEventPause, event.Type)
+	assert.False(t, event.Time.IsZero())
+	event = <-second
+	assert.Equal(t, snowflake.ID(1), event.GuildID)
+}
+
+func Test_EventHub_Publish_DropsWhenSubscriberIsFull(t *testing.T) {
+	hub := newEventHub()
+	ch, cancel := hub.Subscribe()
+	defer cancel()
+
+	for range eventSubscriberBuffer + 5 {
+		hub.Publish(PlayerEvent{Type: PlayerEventResume})
+	}
+
+	assert.Equal(t, eventSubscriberBuffer, len(ch))
+}
+
+func Test_EventHub_Cancel_ClosesChannel(t *testing.T) {
+	hub := newEventHub()
+	ch, cancel := hub.Subscribe()
+
+	cancel()
+	cancel()
+	hub.Publish(PlayerEvent{Type: PlayerEventResume})
+
+	_, open := <-ch
+	assert.False(t, open)
+}
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/discordtest"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type gatewayFixture struct {
+	*integrationFixture
+	discord *discordtest.Harness
+}
+
+// newGatewayFixture builds a Bot whose Discord client is backed by the discordtest harness and whose Lavalink
+// client is connected to a fake node.
+func newGatewayFixture(t *testing.T) *gatewayFixture {
+	t.Helper()
+	f := &gatewayFixture{
+		integrationFixture: newIntegrationFixture(t),
+		discord:            discordtest.New(t),
+	}
+	client, err := f.bot.newDiscordClient(discordtest.Token(testBotUserID), f.discord.ConfigOpts()...)
+	require.NoError(t, err)
+	f.discord.Attach(client)
+	f.bot.Client = client
+	f.bot.Handlers = f.bot.commandHandlers()
+
+	f.bot.Music = f.newMusicService(client.Caches(), client)
+	return f
+}
+
+func (f *gatewayFixture) replay(name string) {
+	f.discord.Replay(filepath.Join("testdata", "gateway", name+".jsonl"))
+}
+
+func Test_Gateway_UnknownCommandRespondsEphemeral(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	f.replay("unknown_command")
+
+	f.discord.AssertGolden("unknown_command")
+}
+
+func Test_Gateway_PauseWithoutPlayer(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	f.replay("pause_without_player")
+
+	f.discord.AssertGolden("pause_without_player")
+}
+
+func Test_Gateway_PlayRequiresVoiceChannel(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	f.replay("play_not_in_voice")
+
+	f.discord.AssertGolden("play_not_in_voice")
+	assert.Empty(t, f.server.Updates())
+}
+
+func Test_Gateway_Play(t *testing.T) {
+	f := newGatewayFixture(t)
+	uri := "https://example.com/a"
+	f.server.AddTrack(uri, lavalink.Track{Encoded: "a", Info: lavalink.TrackInfo{Title: "Track A", URI: &uri}})
+
+	f.replay("play")
+
+	f.discord.AssertGolden("play")
+	assert.Equal(t, "a", f.server.CurrentTrack(testGuildID))
+}
+
+func Test_Gateway_BotVoiceUpdatesForwardedToLavalink(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.Queues.Get(testGuildID).Add(testTrack("queued"))
+
+	f.replay("bot_joined")
+
+	player, ok := f.server.Player(testGuildID)
+	require.True(t, ok)
+	assert.Equal(t, lavalink.VoiceState{Token: "voice-token", Endpoint: "voice.example.com", SessionID: "bot-session"}, player.Voice)
+	assert.Equal(t, 1, f.bot.Queues.Get(testGuildID).Len())
+
+	f.replay("bot_left")
+
+	_, ok = f.server.Player(testGuildID)
+	assert.False(t, ok)
+	assert.Equal(t, 0, f.bot.Queues.Get(testGuildID).Len())
+	f.discord.AssertGolden("bot_voice_updates")
+}
+
+func Test_Gateway_ListenersLeavingPausesPlayback(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.AloneTimeout = time.Minute
+	f.bot.PausedTimeout = time.Minute
+	f.bot.Music.AutoPause = true
+	f.replay("bot_joined")
+	f.replay("listener_joined")
+
+	// Only a playing track is paused, the player knows it once the node reports it started.
+	f.playTrack(t)
+	assert.NotContains(t, f.bot.idleTimers.remaining()[testGuildID], idleReasonAlone)
+
+	// Only another bot is left in the channel.
+	f.replay("listener_left")
+	assert.Contains(t, f.bot.idleTimers.remaining()[testGuildID], idleReasonAlone)
+	assert.NotContains(t, f.bot.idleTimers.remaining()[testGuildID], idleReasonPaused)
+	player, _ := f.server.Player(testGuildID)
+	assert.True(t, player.Paused)
+
+	f.replay("listener_joined")
+	assert.NotContains(t, f.bot.idleTimers.remaining()[testGuildID], idleReasonAlone)
+	player, _ = f.server.Player(testGuildID)
+	assert.False(t, player.Paused)
+}
+
+func Test_Gateway_PauseArmsPausedTimeout(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.PausedTimeout = time.Minute
+	f.playTrack(t)
+
+	f.replay("pause_without_player")
+	player, _ := f.server.Player(testGuildID)
+	assert.True(t, player.Paused)
+	assert.Contains(t, f.bot.idleTimers.remaining()[testGuildID], idleReasonPaused)
+
+	f.replay("pause_without_player")
+	player, _ = f.server.Player(testGuildID)
+	assert.False(t, player.Paused)
+	assert.NotContains(t, f.bot.idleTimers.remaining()[testGuildID], idleReasonPaused)
+}
+
+func Test_Gateway_DebugDefersBeforeCheckingOwner(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.discord.Transport.Respond(http.MethodGet, "/applications/@me", discordtest.Response{
+		Status: http.StatusOK,
+		Body:   map[string]any{"id": "100", "owner": map[string]any{"id": "2", "username": "owner"}},
+	})
+
+	f.replay("debug")
+
+	requests := f.discord.Transport.Requests()
+	require.Len(t, requests, 3)
+	assert.Equal(t, "/interactions/531/token-debug/callback", requests[0].Path)
+	assert.JSONEq(t, `{"type":5}`, string(requests[0].Body))
+	assert.Equal(t, "/applications/@me", requests[1].Path)
+	assert.Equal(t, http.MethodPatch, requests[2].Method)
+	assert.Contains(t, string(requests[2].Body), "Node `test` Capabilities")
+}
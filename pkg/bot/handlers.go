@@ -2,11 +2,15 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"regexp"
 	"time"
 
+	"go-discord-music/pkg/format"
+
 	"github.com/Cyb3r-Jak3/common/v5"
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
@@ -35,35 +39,22 @@ var bassBoost = &lavalink.Equalizer{
 var (
 	urlPattern    = regexp.MustCompile("^https?://[-a-zA-Z0-9+&@#/%?=~_|!:,.;]*[-a-zA-Z0-9+&@#/%=~_|]?")
 	searchPattern = regexp.MustCompile(`^(.{2})search:(.+)`)
+	// localPattern matches identifiers Lavalink would load from its own files or other non-HTTP sources: URLs of
+	// other schemes and file paths.
+	localPattern = regexp.MustCompile(`^(?:[a-zA-Z][a-zA-Z0-9+.-]*://|file:|[a-zA-Z]:[/\\]|[/\\~]|\.\.?[/\\])`)
 )
 
-func (b *Bot) shuffle(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	queue := b.Queues.Get(*event.GuildID())
-	if queue == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
-	queue.Shuffle()
+func (b *Bot) shuffle(_ context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	b.Music.Shuffle(*event.GuildID())
 	return event.CreateMessage(discord.MessageCreate{
 		Content: "Queue shuffled",
 	})
 }
 
-func (b *Bot) volume(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
+func (b *Bot) volume(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
 	volume := data.Int("volume")
-	if err := player.Update(context.TODO(), lavalink.WithVolume(volume)); err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while setting volume: `%s`", err),
-		})
+	if err := b.Music.SetVolume(ctx, *event.GuildID(), volume); err != nil {
+		return serviceError("setting volume", err)
 	}
 
 	return event.CreateMessage(discord.MessageCreate{
@@ -71,51 +62,10 @@ func (b *Bot) volume(event *events.ApplicationCommandInteractionCreate, data dis
 	})
 }
 
-func (b *Bot) seek(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
-	position := data.Int("position")
-	unit, ok := data.OptInt("unit")
-	if !ok {
-		unit = 1
-	}
-	finalPosition := lavalink.Duration(position * unit)
-	if err := player.Update(context.TODO(), lavalink.WithPosition(finalPosition)); err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while seeking: `%s`", err),
-		})
-	}
-
-	return event.CreateMessage(discord.MessageCreate{
-		Content: fmt.Sprintf("Seeked to `%s`", formatPosition(finalPosition)),
-	})
-}
-
-func (b *Bot) bassBoost(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
+func (b *Bot) bassBoost(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
 	enabled := data.Bool("enabled")
-	filters := player.Filters()
-	if enabled {
-		filters.Equalizer = bassBoost
-	} else {
-		filters.Equalizer = nil
-	}
-
-	if err := player.Update(context.TODO(), lavalink.WithFilters(filters)); err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while setting bass boost: `%s`", err),
-		})
+	if err := b.Music.SetBassBoost(ctx, *event.GuildID(), enabled); err != nil {
+		return serviceError("setting bass boost", err)
 	}
 
 	return event.CreateMessage(discord.MessageCreate{
@@ -123,48 +73,25 @@ func (b *Bot) bassBoost(event *events.ApplicationCommandInteractionCreate, data
 	})
 }
 
-func (b *Bot) skip(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	queue := b.Queues.Get(*event.GuildID())
-	if player == nil || queue == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
+func (b *Bot) skip(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
 	amount, ok := data.OptInt("amount")
 	if !ok {
 		amount = 1
 	}
 
-	track, ok := queue.Skip(amount)
-	currentTrack := player.Track()
-	if currentTrack != nil {
-		playerUpdateErr := player.Update(context.TODO(), lavalink.WithNullTrack())
-		if playerUpdateErr != nil {
-			return event.CreateMessage(discord.MessageCreate{
-				Content: fmt.Sprintf("Error while skipping current track: `%s`", playerUpdateErr),
-			})
-		}
-		if !ok {
-			b.idleTimes[*event.GuildID()] = time.Now()
+	result, err := b.Music.Skip(ctx, *event.GuildID(), amount)
+	if err != nil {
+		return serviceError("skipping track", err)
+	}
+
+	if result.Previous != nil {
+		if result.QueueEmpty {
 			return event.CreateMessage(discord.MessageCreate{
-				Content: fmt.Sprintf("Skipped `%d` track(s), but no next track available, current track was: [`%s`](<%s>)", amount, currentTrack.Info.Title, *currentTrack.Info.URI),
+				Content: fmt.Sprintf("Skipped `%d` track(s), but no next track available, current track was: %s", amount, format.Link(result.Previous.Info)),
 			})
 		}
 		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Skipped `%d` track(s), current track was: [`%s`](<%s>)", amount, currentTrack.Info.Title, *currentTrack.Info.URI),
-		})
-	}
-	if !ok {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No tracks in queue",
-		})
-	}
-
-	if err := player.Update(context.TODO(), lavalink.WithTrack(track)); err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while skipping track: `%s`", err),
+			Content: fmt.Sprintf("Skipped `%d` track(s), current track was: %s", amount, format.Link(result.Previous.Info)),
 		})
 	}
 
@@ -173,93 +100,66 @@ func (b *Bot) skip(event *events.ApplicationCommandInteractionCreate, data disco
 	})
 }
 
-func (b *Bot) queueType(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
-	queue := b.Queues.Get(*event.GuildID())
-	if queue == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
+func (b *Bot) queueType(_ context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	queueType := QueueType(data.String("type"))
+	if err := b.Music.SetQueueType(*event.GuildID(), queueType); err != nil {
+		return serviceError("setting queue type", err)
 	}
-
-	queue.Type = QueueType(data.String("type"))
 	return event.CreateMessage(discord.MessageCreate{
-		Content: fmt.Sprintf("Queue type set to `%s`", queue.Type),
+		Content: fmt.Sprintf("Queue type set to `%s`", queueType),
 	})
 }
 
-func (b *Bot) clearQueue(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	queue := b.Queues.Get(*event.GuildID())
-	if queue == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
-	queue.Clear()
+func (b *Bot) clearQueue(_ context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	b.Music.ClearQueue(*event.GuildID())
 	return event.CreateMessage(discord.MessageCreate{
 		Content: "Queue cleared",
 	})
 }
 
-func (b *Bot) queue(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	queue := b.Queues.Get(*event.GuildID())
-	if queue == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No queue found",
-		})
-	}
-
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
+func (b *Bot) queue(_ context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	state := b.Music.Queue(*event.GuildID())
 	var tracks string
-	currentTrack := player.Track()
-	if currentTrack != nil {
-		tracks += fmt.Sprintf("Current track: [`%s`](<%s>)\n", currentTrack.Info.Title, *currentTrack.Info.URI)
+	if state.Current != nil {
+		tracks += fmt.Sprintf("Current track: %s\n", format.Link(state.Current.Info))
 	} else {
 		tracks += "No current track\n"
 	}
 
-	if len(queue.Tracks) == 0 {
+	if len(state.Tracks) == 0 {
 		return event.CreateMessage(discord.MessageCreate{
 			Content: "No tracks in queue",
 		})
 	}
 
-	for i, track := range queue.Tracks {
-		tracks += fmt.Sprintf("%d. [`%s`](<%s>)\n", i+1, track.Info.Title, *track.Info.URI)
+	for i, track := range state.Tracks {
+		tracks += fmt.Sprintf("%d. %s `%s`\n", i+1, format.Link(track.Info), format.Length(track.Info))
 	}
 
 	return event.CreateMessage(discord.MessageCreate{
-		Content: fmt.Sprintf("Queue `%s`:\n%s", queue.Type, tracks),
+		Content: fmt.Sprintf("Queue `%s`, `%d` tracks, `%s` total:\n%s", state.Type, len(state.Tracks), format.Total(state.Tracks), tracks),
 	})
 }
 
-func (b *Bot) players(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+func (b *Bot) players(_ context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
 	var description string
-	b.Lavalink.ForPlayers(func(player disgolink.Player) {
-		description += fmt.Sprintf("GuildID: `%s`\n", player.GuildID())
-	})
+	for _, guildID := range b.Music.GuildIDs() {
+		description += fmt.Sprintf("GuildID: `%s`\n", guildID)
+	}
 
 	return event.CreateMessage(discord.MessageCreate{
 		Content: fmt.Sprintf("Players:\n%s", description),
 	})
 }
 
-func (b *Bot) pause(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
-	if err := player.Update(context.TODO(), lavalink.WithPaused(!player.Paused())); err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while pausing: `%s`", err),
-		})
+func (b *Bot) pause(ctx context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	paused, err := b.Music.Pause(ctx, *event.GuildID(), nil)
+	if err != nil {
+		return serviceError("pausing", err)
 	}
 
 	status := "playing"
-	if player.Paused() {
+	if paused {
 		status = "paused"
 	}
 	return event.CreateMessage(discord.MessageCreate{
@@ -267,18 +167,9 @@ func (b *Bot) pause(event *events.ApplicationCommandInteractionCreate, _ discord
 	})
 }
 
-func (b *Bot) stop(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
-	if err := player.Update(context.TODO(), lavalink.WithNullTrack()); err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while stopping: `%s`", err),
-		})
+func (b *Bot) stop(ctx context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	if err := b.Music.Stop(ctx, *event.GuildID()); err != nil {
+		return serviceError("stopping", err)
 	}
 
 	return event.CreateMessage(discord.MessageCreate{
@@ -286,24 +177,9 @@ func (b *Bot) stop(event *events.ApplicationCommandInteractionCreate, _ discord.
 	})
 }
 
-func (b *Bot) connect(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "Player already connected",
-		})
-	}
-	voiceState, ok := b.Client.Caches().VoiceState(*event.GuildID(), event.User().ID)
-	if !ok {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "You need to be in a voice channel to use this command",
-		})
-	}
-	err := b.Client.UpdateVoiceState(context.TODO(), *event.GuildID(), voiceState.ChannelID, false, false)
-	if err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while connecting: `%s`", err),
-		})
+func (b *Bot) connect(ctx context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	if err := b.Music.Connect(ctx, *event.GuildID(), event.User().ID); err != nil {
+		return serviceError("connecting", err)
 	}
 
 	return event.CreateMessage(discord.MessageCreate{
@@ -311,166 +187,113 @@ func (b *Bot) connect(event *events.ApplicationCommandInteractionCreate, _ disco
 	})
 }
 
-func (b *Bot) disconnect(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
+func (b *Bot) disconnect(ctx context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	if b.alwaysOn(*event.GuildID()) != nil {
+		return userErrorf("24/7 mode is enabled in this server, disable it with `/24-7` first")
 	}
-
-	if err := b.Client.UpdateVoiceState(context.TODO(), *event.GuildID(), nil, false, false); err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error while disconnecting: `%s`", err),
-		})
+	if err := b.Music.Disconnect(ctx, *event.GuildID()); err != nil {
+		return serviceError("disconnecting", err)
 	}
-	delete(b.idleTimes, *event.GuildID())
 
 	return event.CreateMessage(discord.MessageCreate{
 		Content: "Player disconnected",
 	})
 }
 
-func (b *Bot) nowPlaying(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
-	player := b.Lavalink.ExistingPlayer(*event.GuildID())
-	if player == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No player found",
-		})
-	}
-
-	track := player.Track()
-	if track == nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "No track found",
-		})
+func (b *Bot) nowPlaying(_ context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	nowPlaying, err := b.Music.NowPlaying(*event.GuildID())
+	if err != nil {
+		return serviceError("fetching the current track", err)
 	}
 
+	track := nowPlaying.Track
 	return event.CreateMessage(discord.MessageCreate{
-		Content: fmt.Sprintf("Now playing: [`%s`](<%s>)\n\n %s / %s", track.Info.Title, *track.Info.URI, formatPosition(player.Position()), formatPosition(track.Info.Length)),
+		Content: fmt.Sprintf("Now playing: %s\n\n%s", format.Link(track.Info), format.Progress(nowPlaying.Position, track.Info)),
 	})
 }
 
-func formatPosition(position lavalink.Duration) string {
-	if position == 0 {
-		return "0:00"
-	}
-	return fmt.Sprintf("%d:%02d", position.Minutes(), position.SecondsPart())
-}
-
-func (b *Bot) play(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
-	identifier := data.String("identifier")
-	if source, ok := data.OptString("source"); ok {
-		identifier = lavalink.SearchType(source).Apply(identifier)
-	} else if !urlPattern.MatchString(identifier) && !searchPattern.MatchString(identifier) {
-		identifier = lavalink.SearchTypeYouTube.Apply(identifier)
-	}
-
-	voiceState, ok := b.Client.Caches().VoiceState(*event.GuildID(), event.User().ID)
-	if !ok {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "You need to be in a voice channel to use this command",
-		})
+func (b *Bot) play(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	channelID, err := b.Music.JoinableChannel(*event.GuildID(), event.User().ID)
+	if err != nil {
+		return serviceError("joining your voice channel", err)
 	}
 
-	if err := event.DeferCreateMessage(false); err != nil {
+	if err = event.DeferCreateMessage(false); err != nil {
 		return err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	var toPlay *lavalink.Track
-	b.Lavalink.BestNode().LoadTracksHandler(ctx, identifier, disgolink.NewResultHandler(
-		func(track lavalink.Track) {
-			_, _ = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
-				Content: common.Ptr(fmt.Sprintf("Loaded track: [`%s`](<%s>)", track.Info.Title, *track.Info.URI)),
-			})
-			toPlay = &track
-		},
-		func(playlist lavalink.Playlist) {
-			_, _ = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
-				Content: common.Ptr(fmt.Sprintf("Loaded playlist: `%s` with `%d` tracks", playlist.Info.Name, len(playlist.Tracks))),
-			})
-			toPlay = &playlist.Tracks[0]
-		},
-		func(tracks []lavalink.Track) {
-			_, _ = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
-				Content: common.Ptr(fmt.Sprintf("Loaded search result: [`%s`](<%s>)", tracks[0].Info.Title, *tracks[0].Info.URI)),
-			})
-			toPlay = &tracks[0]
-		},
-		func() {
-			_, _ = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
-				Content: common.Ptr(fmt.Sprintf("Nothing found for: `%s`", identifier)),
-			})
-		},
-		func(err error) {
-			_, _ = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
-				Content: common.Ptr(fmt.Sprintf("Error while looking up query: `%s`", err)),
-			})
-		},
-	))
-	if toPlay == nil {
-		return nil
-	}
+	identifier := data.String("identifier")
+	result, err := b.Music.Play(ctx, *event.GuildID(), channelID, event.User().ID, identifier, data.String("source"))
+	var content string
+	switch {
+	case errors.Is(err, ErrNoMatches):
+		return userErrorf("Nothing found for: `%s`", identifier)
+	case err != nil:
+		return serviceError("playing track", err)
+	case result.PlaylistName != "" && result.Queued:
+		content = fmt.Sprintf("Queued playlist: `%s` with `%d` tracks", result.PlaylistName, len(result.Tracks))
+	case result.PlaylistName != "":
+		content = fmt.Sprintf("Loaded playlist: `%s` with `%d` tracks", result.PlaylistName, len(result.Tracks))
+	case result.Queued:
+		content = fmt.Sprintf("Queued at position `%d`: %s", result.QueuePosition, format.Link(result.Track.Info))
+	case result.Search:
+		content = fmt.Sprintf("Loaded search result: %s", format.Link(result.Track.Info))
+	default:
+		content = fmt.Sprintf("Loaded track: %s", format.Link(result.Track.Info))
+	}
+	content += rejectionNote(result.Rejected)
+	_, err = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: common.Ptr(content),
+	})
+	return err
+}
 
-	if err := b.Client.UpdateVoiceState(context.TODO(), *event.GuildID(), voiceState.ChannelID, false, false); err != nil {
+func (b *Bot) debug(ctx context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	// Checking the owner and querying the nodes may take longer than Discord waits for a response.
+	if err := event.DeferCreateMessage(false); err != nil {
 		return err
 	}
-	b.logger.Infof("Found track: %s", toPlay.Info.Title)
-
-	playErr := b.Lavalink.Player(*event.GuildID()).Update(context.TODO(), lavalink.WithTrack(*toPlay))
-	if playErr != nil {
-		_, _ = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
-			Content: common.Ptr(fmt.Sprintf("Error while playing track: `%s`", playErr)),
-		})
-		return playErr
-	}
-
-	return playErr
-}
-
-func (b *Bot) debug(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
 	selfInfo, err := b.Client.Rest().GetCurrentApplication()
 	if err != nil {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: fmt.Sprintf("Error fetching application info: `%s`", err),
-		})
+		return fmt.Errorf("error fetching application info: %w", err)
 	}
-
 	if event.User().ID != selfInfo.Owner.ID {
-		return event.CreateMessage(discord.MessageCreate{
-			Content: "You are not allowed to use this command",
-		})
+		return userErrorf("You are not allowed to use this command")
 	}
 
-	var gatewayPing string
-	if event.Client().HasGateway() {
-		gatewayPing = event.Client().Gateway().Latency().String()
-	}
 	eb := discord.NewEmbedBuilder().
 		SetTitle("Debug Info").
-		AddField("Gateway", gatewayPing, false)
+		AddField("Gateway", b.gatewaysString(event.GuildID()), false)
 
 	cookieString := ""
 	nodeString := ""
+	var capabilityFields []discord.EmbedField
 	b.Lavalink.ForNodes(func(node disgolink.Node) {
 		nodeConfig := node.Config()
-		nodeHost, parseErr := url.Parse(nodeConfig.Address)
+		nodeHost, parseErr := url.Parse(nodeConfig.RestURL())
 		if parseErr != nil {
 			b.logger.Errorf("error parsing node address: %v", parseErr)
 			return
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		nodeVersion, nodeErr := node.Version(ctx)
+		nodeCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		capabilities, nodeErr := b.refreshCapabilities(nodeCtx, node)
 		cancel()
+		nodeVersion := capabilities.Version
 		if nodeErr != nil {
-			b.logger.Errorf("error getting node version: %v", nodeErr)
+			b.logger.Errorf("error getting node info: %v", nodeErr)
 			nodeVersion = "error"
 		}
 		nodeString += fmt.Sprintf("Node: `%s` Address: `%s` Secure: `%t` Version: %s\n", nodeConfig.Name, nodeConfig.Address, nodeConfig.Secure, nodeVersion)
-		cookies := b.HTTPClient.Jar.Cookies(nodeHost)
+		if nodeErr == nil {
+			capabilityFields = append(capabilityFields, discord.EmbedField{
+				Name:  fmt.Sprintf("Node `%s` Capabilities", nodeConfig.Name),
+				Value: capabilitiesString(capabilities),
+			})
+		}
+		var cookies []*http.Cookie
+		if b.HTTPClient != nil && b.HTTPClient.Jar != nil {
+			cookies = b.HTTPClient.Jar.Cookies(nodeHost)
+		}
 		if len(cookies) == 0 {
 			cookieString += fmt.Sprintf("Node: `%s` (%s) has no cookies\n", nodeConfig.Name, nodeHost)
 			return
@@ -480,33 +303,29 @@ func (b *Bot) debug(event *events.ApplicationCommandInteractionCreate, _ discord
 		}
 	})
 	timerString := ""
-	for guild, timer := range b.idleTimes {
-		if time.Since(timer) > b.IdleTimeout {
-			disconnectErr := b.Client.UpdateVoiceState(context.TODO(), guild, nil, false, false)
-			if disconnectErr != nil {
-				b.logger.Errorf("error updating voice state for guild %s: %v", guild, disconnectErr)
-			}
-			delete(b.idleTimes, guild)
-			b.logger.Infof("Guild `%s` has been idle for more than %s, disconnected\n", guild, b.IdleTimeout)
-		} else {
-			b.logger.Infof("idle timeout for guild %s, %d", guild, timer.Unix())
-			timerString += fmt.Sprintf("Guild `%s`: idle time remaining: %s\n", guild, b.IdleTimeout-time.Since(timer).Round(time.Second))
+	for guild, reasons := range b.idleTimers.remaining() {
+		for reason, remaining := range reasons {
+			timerString += fmt.Sprintf("Guild `%s` (%s): idle time remaining: %s\n", guild, reason, remaining.Round(time.Second))
 		}
 	}
 	if timerString == "" {
-		timerString = "No guilds are idle"
+		timerString = "No guilds are idle\n"
 	}
+	timerString += fmt.Sprintf("Timeouts: no track `%s`, alone `%s`, paused `%s` (0s is disabled)", b.IdleTimeout, b.AloneTimeout, b.PausedTimeout)
 	eb.AddField("Idle Times", timerString, false)
 	eb.AddField("Nodes", nodeString, false)
+	for _, field := range capabilityFields {
+		eb.AddField(field.Name, field.Value, false)
+	}
 	eb.AddField("HTTP Client Cookies", cookieString, false)
 	eb.Timestamp = common.Ptr(time.Now())
-	return event.Respond(discord.InteractionResponseTypeCreateMessage, discord.NewMessageCreateBuilder().
-		SetEmbeds(eb.Build()).
-		Build(),
-	)
+	_, err = b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Embeds: &[]discord.Embed{eb.Build()},
+	})
+	return err
 }
 
-func (b *Bot) source(event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+func (b *Bot) source(_ context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
 	return event.CreateMessage(discord.MessageCreate{
 		Content: "Source for the bot: [Cyb3r-Jak3/go-discord-music](https://github.com/Cyb3r-Jak3/go-discord-music)\n",
 	})
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgolink/v3/disgolink"
+)
+
+// GatewayHealth describes the state of the Discord gateway connection.
+type GatewayHealth struct {
+	Status    string `json:"status"`
+	Connected bool   `json:"connected"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// ShardHealth describes the gateway connection of one shard.
+type ShardHealth struct {
+	ID int `json:"id"`
+	GatewayHealth
+}
+
+// NodeHealth describes the state of a single Lavalink node.
+type NodeHealth struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Players int    `json:"players"`
+}
+
+// Readiness is the body returned by the readiness endpoint.
+type Readiness struct {
+	Ready   bool          `json:"ready"`
+	Gateway GatewayHealth `json:"gateway"`
+	// Shards is only set when the bot is sharded. Gateway then summarises them: it is connected when all shards
+	// are, with the highest latency.
+	Shards []ShardHealth `json:"shards,omitempty"`
+	Nodes  []NodeHealth  `json:"nodes"`
+}
+
+// Readiness reports whether the bot is connected to the Discord gateway and has at least one usable Lavalink node.
+func (b *Bot) Readiness() Readiness {
+	readiness := Readiness{
+		Gateway: GatewayHealth{Status: gateway.StatusUnconnected.String()},
+		Nodes:   make([]NodeHealth, 0),
+	}
+	if b.Client.HasGateway() {
+		readiness.Gateway = gatewayHealth(b.Client.Gateway())
+	}
+	if b.Client.HasShardManager() {
+		readiness.Gateway = GatewayHealth{Status: gateway.StatusReady.String(), Connected: true}
+		for _, shardID := range b.shardIDs() {
+			shard := ShardHealth{ID: shardID, GatewayHealth: gatewayHealth(b.Client.ShardManager().Shard(shardID))}
+			readiness.Shards = append(readiness.Shards, shard)
+			if !shard.Connected {
+				readiness.Gateway.Status, readiness.Gateway.Connected = shard.Status, false
+			}
+			readiness.Gateway.LatencyMS = max(readiness.Gateway.LatencyMS, shard.LatencyMS)
+		}
+		if len(readiness.Shards) == 0 {
+			readiness.Gateway = GatewayHealth{Status: gateway.StatusUnconnected.String()}
+		}
+	}
+
+	nodeAvailable := false
+	b.Lavalink.ForNodes(func(node disgolink.Node) {
+		status := node.Status()
+		if status == disgolink.StatusConnected {
+			nodeAvailable = true
+		}
+		readiness.Nodes = append(readiness.Nodes, NodeHealth{
+			Name:    node.Config().Name,
+			Status:  string(status),
+			Players: node.Stats().Players,
+		})
+	})
+
+	readiness.Ready = readiness.Gateway.Connected && nodeAvailable
+	return readiness
+}
+
+func gatewayHealth(gw gateway.Gateway) GatewayHealth {
+	return GatewayHealth{
+		Status:    gw.Status().String(),
+		Connected: gw.Status() == gateway.StatusReady,
+		LatencyMS: gw.Latency().Milliseconds(),
+	}
+}
+
+// handleHealthz reports that the process is alive and serving requests.
+func (b *Bot) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	b.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReadyz reports whether the bot can serve music, returning 503 when it cannot.
+func (b *Bot) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	readiness := b.Readiness()
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	b.writeJSON(w, status, readiness)
+}
+
+func (b *Bot) writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		b.logger.Errorf("error writing HTTP response: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-discord-music/pkg/discordtest"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgo/sharding"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeShardManager is a sharding.ShardManager over fixed gateways.
+type fakeShardManager map[int]gateway.Gateway
+
+var _ sharding.ShardManager = fakeShardManager(nil)
+
+func (fakeShardManager) Open(context.Context)                 {}
+func (fakeShardManager) Close(context.Context)                {}
+func (fakeShardManager) OpenShard(context.Context, int) error { return nil }
+func (fakeShardManager) CloseShard(context.Context, int)      {}
+
+func (m fakeShardManager) ShardByGuildID(guildID snowflake.ID) gateway.Gateway {
+	return m[sharding.ShardIDByGuild(guildID, len(m))]
+}
+
+func (m fakeShardManager) Shard(shardID int) gateway.Gateway {
+	return m[shardID]
+}
+
+func (m fakeShardManager) Shards() map[int]gateway.Gateway {
+	shards := make(map[int]gateway.Gateway, len(m))
+	for id, shard := range m {
+		shards[id] = shard
+	}
+	return shards
+}
+
+// readyz requests the readiness endpoint and decodes its body.
+func (f *gatewayFixture) readyz(t *testing.T) (int, Readiness) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	f.bot.handleReadyz(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var readiness Readiness
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &readiness))
+	return rec.Code, readiness
+}
+
+func Test_Healthz(t *testing.T) {
+	f := newGatewayFixture(t)
+	rec := httptest.NewRecorder()
+
+	f.bot.handleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func Test_Gateway_Readyz(t *testing.T) {
+	f := newGatewayFixture(t)
+
+	code, readiness := f.readyz(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, Readiness{
+		Ready:   true,
+		Gateway: GatewayHealth{Status: "Ready", Connected: true},
+		Nodes:   []NodeHealth{{Name: "test", Status: "CONNECTED"}},
+	}, readiness)
+
+	f.discord.Gateway.SetStatus(gateway.StatusDisconnected)
+	code, readiness = f.readyz(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, GatewayHealth{Status: "Disconnected"}, readiness.Gateway)
+
+	f.discord.Gateway.SetStatus(gateway.StatusReady)
+	f.server.CloseClient(f.bot.Lavalink)
+	code, readiness = f.readyz(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, readiness.Ready)
+	assert.True(t, readiness.Gateway.Connected)
+	assert.Equal(t, []NodeHealth{{Name: "test", Status: "DISCONNECTED"}}, readiness.Nodes)
+}
+
+func Test_Gateway_ReadyzShards(t *testing.T) {
+	f := newGatewayFixture(t)
+	up, down := &discordtest.Gateway{}, &discordtest.Gateway{}
+	up.SetStatus(gateway.StatusReady)
+	down.SetStatus(gateway.StatusResuming)
+	client, err := f.bot.newDiscordClient(discordtest.Token(testBotUserID), append(f.discord.ConfigOpts(),
+		bot.WithShardManager(fakeShardManager{0: up, 1: down}),
+	)...)
+	require.NoError(t, err)
+	f.bot.Client = client
+
+	code, readiness := f.readyz(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, readiness.Ready)
+	assert.Equal(t, GatewayHealth{Status: "Resuming"}, readiness.Gateway)
+	assert.Equal(t, []ShardHealth{
+		{ID: 0, GatewayHealth: GatewayHealth{Status: "Ready", Connected: true}},
+		{ID: 1, GatewayHealth: GatewayHealth{Status: "Resuming"}},
+	}, readiness.Shards)
+
+	down.SetStatus(gateway.StatusReady)
+	code, readiness = f.readyz(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, readiness.Ready)
+	assert.Equal(t, GatewayHealth{Status: "Ready", Connected: true}, readiness.Gateway)
+	assert.Len(t, readiness.Shards, 2)
+}
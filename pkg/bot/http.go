@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"go-discord-music/pkg/metrics"
+)
+
+// startHTTPServer starts the optional HTTP listener used for metrics and health probes. It is a no-op when no address is configured.
+func (b *Bot) startHTTPServer() {
+	if b.HTTPAddress == "" {
+		return
+	}
+	if err := metrics.Register(&botCollector{bot: b}); err != nil {
+		b.logger.Errorf("error registering bot metrics collector: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", b.handleHealthz)
+	mux.HandleFunc("GET /readyz", b.handleReadyz)
+
+	b.httpServer = &http.Server{
+		Addr:              b.HTTPAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		b.logger.Infof("starting HTTP server on %s", b.HTTPAddress)
+		if err := b.httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			b.logger.Errorf("error running HTTP server: %v", err)
+		}
+	}()
+}
+
+func (b *Bot) stopHTTPServer(ctx context.Context) {
+	if b.httpServer == nil {
+		return
+	}
+	if err := b.httpServer.Shutdown(ctx); err != nil {
+		b.logger.Errorf("error shutting down HTTP server: %v", err)
+	}
+}
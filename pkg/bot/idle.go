@@ -0,0 +1,236 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-discord-music/pkg/metrics"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// idleReason is why a guild is considered idle. Each reason has its own timeout.
+type idleReason string
+
+const (
+	// idleReasonNoTrack is set when the queue is exhausted and nothing is playing.
+	idleReasonNoTrack idleReason = "no_track"
+	// idleReasonAlone is set when no listeners are left in the bot's voice channel.
+	idleReasonAlone idleReason = "alone"
+	// idleReasonPaused is set when the player was paused by a user.
+	idleReasonPaused idleReason = "paused"
+)
+
+// idleTimeout returns the timeout for reason. A zero timeout disables the reason.
+func (b *Bot) idleTimeout(reason idleReason) time.Duration {
+	switch reason {
+	case idleReasonAlone:
+		return b.AloneTimeout
+	case idleReasonPaused:
+		return b.PausedTimeout
+	default:
+		return b.IdleTimeout
+	}
+}
+
+// markIdle arms the guild's idle timer for reason. An armed timer for the same reason is kept so repeated
+// events don't extend the timeout. Guilds in 24/7 mode never become idle.
+func (b *Bot) markIdle(guildID snowflake.ID, reason idleReason) {
+	if b.alwaysOn(guildID) != nil {
+		return
+	}
+	b.idleTimers.arm(guildID, reason)
+}
+
+// clearIdle cancels the given idle timers for the guild, or all of them when no reasons are given.
+func (b *Bot) clearIdle(guildID snowflake.ID, reasons ...idleReason) {
+	b.idleTimers.cancel(guildID, reasons...)
+}
+
+// onIdleTimeout leaves the voice channel of a guild whose idle timer fired.
+func (b *Bot) onIdleTimeout(guildID snowflake.ID, reason idleReason) {
+	b.logger.Infof("idle timeout for guild %s, reason: %s", guildID, reason)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.Music.Leave(ctx, guildID); err != nil {
+		b.logger.Errorf("error updating voice state for guild %s: %v", guildID, err)
+		return
+	}
+	metrics.IdleDisconnects.WithLabelValues(string(reason)).Inc()
+	b.logger.Infof("disconnected from voice channel for guild %s due to idle timeout", guildID)
+}
+
+// leaveIdleGuilds stops all idle timers and leaves the voice channels of the guilds which were idle.
+func (b *Bot) leaveIdleGuilds(ctx context.Context) {
+	for _, guildID := range b.idleTimers.stop() {
+		b.logger.Infof("removing remaining idle timeout for guild %s", guildID)
+		if err := b.Music.Leave(ctx, guildID); err != nil {
+			b.logger.Errorf("error updating voice state for guild %s: %v", guildID, err)
+		} else {
+			b.logger.Infof("disconnected from voice channel for guild %s due to shutdown", guildID)
+		}
+	}
+}
+
+// checkListeners counts the listeners in the bot's voice channel in the guild and updates idle tracking
+// and auto-pause accordingly.
+func (b *Bot) checkListeners(ctx context.Context, guildID snowflake.ID) {
+	selfState, ok := b.Client.Caches().VoiceState(guildID, b.Client.ApplicationID())
+	if !ok || selfState.ChannelID == nil {
+		return
+	}
+	listeners := b.channelListeners(guildID, *selfState.ChannelID)
+	b.logger.Debugf("guild %s has %d listeners in voice channel %s", guildID, listeners, *selfState.ChannelID)
+	if err := b.Music.UpdateListeners(ctx, guildID, listeners); err != nil {
+		b.logger.Errorf("error updating listeners for guild %s: %v", guildID, err)
+	}
+}
+
+// channelListeners counts the users in a voice channel. Bots, including this one, are not listeners.
+func (b *Bot) channelListeners(guildID snowflake.ID, channelID snowflake.ID) int {
+	listeners := 0
+	b.Client.Caches().VoiceStatesForEach(guildID, func(state discord.VoiceState) {
+		if state.ChannelID == nil || *state.ChannelID != channelID || state.UserID == b.Client.ApplicationID() {
+			return
+		}
+		if member, found := b.Client.Caches().Member(guildID, state.UserID); found && member.User.Bot {
+			return
+		}
+		listeners++
+	})
+	return listeners
+}
+
+// clock abstracts time so idle timers can be tested without waiting.
+type clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func()) stopper
+}
+
+// stopper is a timer which can be cancelled.
+type stopper interface {
+	Stop() bool
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) AfterFunc(d time.Duration, f func()) stopper { return time.AfterFunc(d, f) }
+
+// idleTimer is an armed timer for one guild and idle reason.
+type idleTimer struct {
+	deadline time.Time
+	timer    stopper
+}
+
+// idleScheduler keeps one timer per guild and idle reason. When any timer of a guild fires, all timers of
+// the guild are cancelled and onExpire is called. Reasons with a zero timeout are never armed.
+type idleScheduler struct {
+	mu       sync.Mutex
+	clock    clock
+	timeout  func(reason idleReason) time.Duration
+	onExpire func(guildID snowflake.ID, reason idleReason)
+	timers   map[snowflake.ID]map[idleReason]*idleTimer
+	stopped  bool
+}
+
+func newIdleScheduler(c clock, timeout func(reason idleReason) time.Duration, onExpire func(guildID snowflake.ID, reason idleReason)) *idleScheduler {
+	return &idleScheduler{
+		clock:    c,
+		timeout:  timeout,
+		onExpire: onExpire,
+		timers:   make(map[snowflake.ID]map[idleReason]*idleTimer),
+	}
+}
+
+func (s *idleScheduler) arm(guildID snowflake.ID, reason idleReason) {
+	timeout := s.timeout(reason)
+	if timeout <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	reasons, ok := s.timers[guildID]
+	if !ok {
+		reasons = make(map[idleReason]*idleTimer)
+		s.timers[guildID] = reasons
+	}
+	if _, ok = reasons[reason]; ok {
+		return
+	}
+	t := &idleTimer{deadline: s.clock.Now().Add(timeout)}
+	t.timer = s.clock.AfterFunc(timeout, func() { s.fire(guildID, reason, t) })
+	reasons[reason] = t
+}
+
+func (s *idleScheduler) cancel(guildID snowflake.ID, reasons ...idleReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(reasons) == 0 {
+		s.cancelGuild(guildID)
+		return
+	}
+	for _, reason := range reasons {
+		if t, ok := s.timers[guildID][reason]; ok {
+			t.timer.Stop()
+			delete(s.timers[guildID], reason)
+		}
+	}
+	if len(s.timers[guildID]) == 0 {
+		delete(s.timers, guildID)
+	}
+}
+
+// cancelGuild stops all timers of the guild. The caller must hold mu.
+func (s *idleScheduler) cancelGuild(guildID snowflake.ID) {
+	for _, t := range s.timers[guildID] {
+		t.timer.Stop()
+	}
+	delete(s.timers, guildID)
+}
+
+func (s *idleScheduler) fire(guildID snowflake.ID, reason idleReason, t *idleTimer) {
+	s.mu.Lock()
+	// The timer may have been cancelled or re-armed after it fired but before the lock was acquired.
+	if s.timers[guildID][reason] != t {
+		s.mu.Unlock()
+		return
+	}
+	s.cancelGuild(guildID)
+	s.mu.Unlock()
+	s.onExpire(guildID, reason)
+}
+
+// remaining returns the time left on every armed timer.
+func (s *idleScheduler) remaining() map[snowflake.ID]map[idleReason]time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.clock.Now()
+	result := make(map[snowflake.ID]map[idleReason]time.Duration, len(s.timers))
+	for guildID, reasons := range s.timers {
+		result[guildID] = make(map[idleReason]time.Duration, len(reasons))
+		for reason, t := range reasons {
+			result[guildID][reason] = t.deadline.Sub(now)
+		}
+	}
+	return result
+}
+
+// stop cancels all timers and returns the guilds which had any armed. No timers are armed afterwards.
+func (s *idleScheduler) stop() []snowflake.ID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+	guildIDs := make([]snowflake.ID, 0, len(s.timers))
+	for guildID := range s.timers {
+		guildIDs = append(guildIDs, guildID)
+		s.cancelGuild(guildID)
+	}
+	return guildIDs
+}
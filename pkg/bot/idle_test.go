@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a clock whose timers only fire when the clock is advanced.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *fakeClock
+	deadline time.Time
+	f        func()
+	stopped  bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) stopper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := !t.stopped
+	t.stopped = true
+	return wasActive
+}
+
+// Advance moves the clock forward and runs the timers which are due, in deadline order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	var due []*fakeTimer
+	for _, t := range c.timers {
+		if !t.stopped && !t.deadline.After(c.now) {
+			t.stopped = true
+			due = append(due, t)
+		}
+	}
+	c.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].deadline.Before(due[j].deadline) })
+	for _, t := range due {
+		t.f()
+	}
+}
+
+type expiry struct {
+	guildID snowflake.ID
+	reason  idleReason
+}
+
+func newTestScheduler(timeouts map[idleReason]time.Duration) (*idleScheduler, *fakeClock, *[]expiry) {
+	clock := newFakeClock()
+	var expired []expiry
+	scheduler := newIdleScheduler(clock, func(reason idleReason) time.Duration {
+		return timeouts[reason]
+	}, func(guildID snowflake.ID, reason idleReason) {
+		expired = append(expired, expiry{guildID, reason})
+	})
+	return scheduler, clock, &expired
+}
+
+func Test_IdleScheduler_FiresPerReasonTimeout(t *testing.T) {
+	scheduler, clock, expired := newTestScheduler(map[idleReason]time.Duration{
+		idleReasonNoTrack: 5 * time.Minute,
+		idleReasonAlone:   time.Minute,
+	})
+
+	scheduler.arm(1, idleReasonNoTrack)
+	scheduler.arm(2, idleReasonAlone)
+	scheduler.arm(1, idleReasonAlone)
+
+	clock.Advance(59 * time.Second)
+	assert.Empty(t, *expired)
+
+	clock.Advance(time.Second)
+	assert.ElementsMatch(t, []expiry{{1, idleReasonAlone}, {2, idleReasonAlone}}, *expired)
+	assert.Empty(t, scheduler.remaining(), "all timers of an expired guild are cancelled")
+
+	clock.Advance(10 * time.Minute)
+	assert.Len(t, *expired, 2)
+}
+
+func Test_IdleScheduler_RearmKeepsDeadline(t *testing.T) {
+	scheduler, clock, expired := newTestScheduler(map[idleReason]time.Duration{idleReasonAlone: time.Minute})
+
+	scheduler.arm(1, idleReasonAlone)
+	clock.Advance(30 * time.Second)
+	scheduler.arm(1, idleReasonAlone)
+
+	assert.Equal(t, 30*time.Second, scheduler.remaining()[1][idleReasonAlone])
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, []expiry{{1, idleReasonAlone}}, *expired)
+}
+
+func Test_IdleScheduler_Cancel(t *testing.T) {
+	scheduler, clock, expired := newTestScheduler(map[idleReason]time.Duration{
+		idleReasonNoTrack: time.Minute,
+		idleReasonPaused:  time.Minute,
+	})
+
+	scheduler.arm(1, idleReasonNoTrack)
+	scheduler.arm(1, idleReasonPaused)
+	scheduler.cancel(1, idleReasonPaused)
+	assert.Equal(t, map[snowflake.ID]map[idleReason]time.Duration{1: {idleReasonNoTrack: time.Minute}}, scheduler.remaining())
+
+	scheduler.cancel(1)
+	clock.Advance(time.Hour)
+	assert.Empty(t, *expired)
+	assert.Empty(t, scheduler.remaining())
+}
+
+func Test_IdleScheduler_DisabledTimeoutsAreNotArmed(t *testing.T) {
+	scheduler, clock, expired := newTestScheduler(map[idleReason]time.Duration{})
+
+	scheduler.arm(1, idleReasonNoTrack)
+	clock.Advance(time.Hour)
+
+	assert.Empty(t, *expired)
+	assert.Empty(t, scheduler.remaining())
+}
+
+func Test_IdleScheduler_Stop(t *testing.T) {
+	scheduler, clock, expired := newTestScheduler(map[idleReason]time.Duration{idleReasonNoTrack: time.Minute})
+
+	scheduler.arm(1, idleReasonNoTrack)
+	scheduler.arm(2, idleReasonNoTrack)
+	guildIDs := scheduler.stop()
+	scheduler.arm(3, idleReasonNoTrack)
+	clock.Advance(time.Hour)
+
+	assert.ElementsMatch(t, []snowflake.ID{1, 2}, guildIDs)
+	assert.Empty(t, *expired)
+	assert.Empty(t, scheduler.remaining())
+}
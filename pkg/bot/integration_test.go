@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/lavalinktest"
+
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBotUserID = snowflake.ID(100)
+
+// lockedVoice is a VoiceConnector which is safe to use from the idle cleaner and Lavalink event goroutines.
+type lockedVoice struct {
+	mu       sync.Mutex
+	channels map[snowflake.ID]*snowflake.ID
+	calls    int
+}
+
+func (v *lockedVoice) UpdateVoiceState(_ context.Context, guildID snowflake.ID, channelID *snowflake.ID, _ bool, _ bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.channels[guildID] = channelID
+	v.calls++
+	return nil
+}
+
+func (v *lockedVoice) channel(guildID snowflake.ID) (*snowflake.ID, int) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.channels[guildID], v.calls
+}
+
+// waitForEvent returns the next player event of type eventType, failing the test if none arrives in time.
+func waitForEvent(t *testing.T, events <-chan PlayerEvent, eventType PlayerEventType) PlayerEvent {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == eventType {
+				return event
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for player event", "event type: %s", eventType)
+		}
+	}
+}
+
+type integrationFixture struct {
+	bot    *Bot
+	server *lavalinktest.Server
+	voice  *lockedVoice
+}
+
+// newIntegrationFixture builds a Bot connected to a fake Lavalink node. Discord is replaced by fake voice states.
+func newIntegrationFixture(t *testing.T) *integrationFixture {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	f := &integrationFixture{
+		bot:    newBot(logger),
+		server: lavalinktest.NewServer(t),
+		voice:  &lockedVoice{channels: make(map[snowflake.ID]*snowflake.ID)},
+	}
+	f.bot.Lavalink = f.bot.newLavalinkClient(testBotUserID)
+	// Runs before the server's own cleanup, the client is closed first so it doesn't reconnect.
+	t.Cleanup(func() {
+		f.server.CloseClient(f.bot.Lavalink)
+		f.server.Close()
+	})
+
+	// The node is fully connected once it handled the stats the server sends after the ready message.
+	stats := make(chan struct{}, 1)
+	statsListener := disgolink.NewListenerFunc(func(_ disgolink.Player, _ lavalink.StatsMessage) {
+		select {
+		case stats <- struct{}{}:
+		default:
+		}
+	})
+	f.bot.Lavalink.AddListeners(statsListener)
+	defer f.bot.Lavalink.RemoveListeners(statsListener)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := f.bot.addNode(ctx, f.server.NodeConfig("test"))
+	require.NoError(t, err)
+	select {
+	case <-stats:
+	case <-ctx.Done():
+		require.FailNow(t, "timed out waiting for the lavalink node to connect")
+	}
+
+	f.bot.Music = f.newMusicService(fakeVoiceStates{testUserID: testChannelID}, f.voice)
+	return f
+}
+
+// newMusicService returns the bot's MusicService with players wrapped by lavalinktest.Player.
+func (f *integrationFixture) newMusicService(states VoiceStates, voice VoiceConnector) *MusicService {
+	music := f.bot.newMusicService(states, voice)
+	music.players = fixturePlayers{lavalinkPlayers{client: f.bot.Lavalink}}
+	return music
+}
+
+// fixturePlayers hands out the players of the fixture's Lavalink client wrapped by lavalinktest.Player.
+type fixturePlayers struct {
+	lavalinkPlayers
+}
+
+func (p fixturePlayers) ExistingPlayer(guildID snowflake.ID) Player {
+	if player := p.client.ExistingPlayer(guildID); player != nil {
+		return lavalinktest.Player(player)
+	}
+	return nil
+}
+
+func (p fixturePlayers) Player(guildID snowflake.ID) Player {
+	return lavalinktest.Player(p.client.Player(guildID))
+}
+
+func (p fixturePlayers) ForPlayers(fn func(player Player)) {
+	p.client.ForPlayers(func(player disgolink.Player) {
+		fn(lavalinktest.Player(player))
+	})
+}
+
+func Test_Integration_PlayAdvancesQueueOnTrackEnd(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.server.AddTrack("https://example.com/a", testTrack("a"))
+	f.server.AddTrack("https://example.com/b", testTrack("b"))
+	ctx := context.Background()
+	events, cancel := f.bot.playerEvents.Subscribe()
+	defer cancel()
+
+	result, err := f.bot.Music.Play(ctx, testGuildID, testChannelID, testUserID, "https://example.com/a", "")
+	require.NoError(t, err)
+	assert.False(t, result.Queued)
+	assert.Equal(t, "a", f.server.CurrentTrack(testGuildID))
+
+	// The client only knows about the track once the node reports it started.
+	waitForEvent(t, events, PlayerEventTrackStart)
+
+	result, err = f.bot.Music.Play(ctx, testGuildID, testChannelID, testUserID, "https://example.com/b", "")
+	require.NoError(t, err)
+	assert.True(t, result.Queued)
+	assert.Equal(t, 1, f.bot.Queues.Get(testGuildID).Len())
+
+	require.NoError(t, f.server.FinishTrack(testGuildID, lavalink.TrackEndReasonFinished))
+	event := waitForEvent(t, events, PlayerEventTrackStart)
+	assert.Equal(t, "b", event.Track.Encoded)
+	assert.Equal(t, "b", f.server.CurrentTrack(testGuildID))
+	assert.Equal(t, 0, f.bot.Queues.Get(testGuildID).Len())
+}
+
+func Test_Integration_IdleTimeoutLeavesVoice(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.bot.IdleTimeout = 100 * time.Millisecond
+	f.server.AddTrack("https://example.com/a", testTrack("a"))
+
+	_, err := f.bot.Music.Play(context.Background(), testGuildID, testChannelID, testUserID, "https://example.com/a", "")
+	require.NoError(t, err)
+	channelID, _ := f.voice.channel(testGuildID)
+	require.NotNil(t, channelID)
+
+	require.NoError(t, f.server.FinishTrack(testGuildID, lavalink.TrackEndReasonFinished))
+	assert.Eventually(t, func() bool {
+		channelID, calls := f.voice.channel(testGuildID)
+		return channelID == nil && calls == 2
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func Test_Integration_NodeFailure(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.server.AddTrack("https://example.com/a", testTrack("a"))
+
+	f.server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := f.bot.Music.Play(ctx, testGuildID, testChannelID, testUserID, "https://example.com/a", "")
+	assert.Error(t, err)
+	assert.Empty(t, f.server.CurrentTrack(testGuildID))
+}
@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go-discord-music/pkg/lyrics"
+
+	"github.com/Cyb3r-Jak3/common/v5"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+const (
+	// lyricsPageSize leaves room for the header and footer within Discord's 2000 character message limit.
+	lyricsPageSize = 1800
+	// lyricsLinesBefore and lyricsLinesAfter are the number of synced lines shown around the current line.
+	lyricsLinesBefore = 2
+	lyricsLinesAfter  = 6
+)
+
+var (
+	// lyricsFollowInterval is how often synced lyrics are checked against the player position.
+	lyricsFollowInterval = 2 * time.Second
+	// lyricsFollowDuration is how long synced lyrics follow the player before the message stops updating.
+	lyricsFollowDuration = 10 * time.Minute
+)
+
+// bestNode returns the best Lavalink node, or nil when there is none.
+func (b *Bot) bestNode() disgolink.Node {
+	if b.Lavalink == nil {
+		return nil
+	}
+	return b.Lavalink.BestNode()
+}
+
+func (b *Bot) lyricsCommand(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	guildID := *event.GuildID()
+	nowPlaying, err := b.Music.NowPlaying(guildID)
+	if err != nil {
+		return serviceError("fetching the current track", err)
+	}
+	if err = event.DeferCreateMessage(false); err != nil {
+		return err
+	}
+
+	track := nowPlaying.Track
+	result, err := b.Lyrics.Lyrics(ctx, track)
+
+	var content string
+	switch {
+	case errors.Is(err, lyrics.ErrNotFound):
+		return &UserError{Message: fmt.Sprintf("No lyrics found for `%s`", track.Info.Title), Err: err}
+	case err != nil:
+		return serviceError("fetching lyrics", err)
+	case data.Bool("synced") && len(result.Lines) == 0:
+		return userErrorf("No synced lyrics found for `%s`, use `/lyrics` without `synced` for the plain lyrics", track.Info.Title)
+	case data.Bool("synced"):
+		line := result.LineAt(positionDuration(nowPlaying.Position))
+		content = syncedLyricsContent(track, result, line)
+		go b.followLyrics(event.ApplicationID(), event.Token(), guildID, track, result, line)
+	default:
+		pages := lyrics.Pages(result.Text, lyricsPageSize)
+		if len(pages) == 0 {
+			return userErrorf("No lyrics found for `%s`", track.Info.Title)
+		}
+		page := min(max(data.Int("page"), 1), len(pages))
+		content = fmt.Sprintf("Lyrics for `%s` from %s:\n%s", track.Info.Title, result.Provider, pages[page-1])
+		if len(pages) > 1 {
+			content += fmt.Sprintf("\n\nPage `%d/%d`, use the `page` option to see more", page, len(pages))
+		}
+	}
+	_, updateErr := b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: common.Ptr(content),
+	})
+	return updateErr
+}
+
+// followLyrics updates a synced lyrics response whenever the player reaches a new line. It stops when the
+// track changes, the bot shuts down or lyricsFollowDuration has passed.
+func (b *Bot) followLyrics(applicationID snowflake.ID, token string, guildID snowflake.ID, track lavalink.Track, result *lyrics.Lyrics, line int) {
+	ticker := time.NewTicker(lyricsFollowInterval)
+	defer ticker.Stop()
+	deadline := time.After(lyricsFollowDuration)
+	for {
+		select {
+		case <-b.shutdown:
+			return
+		case <-deadline:
+			return
+		case <-ticker.C:
+		}
+		nowPlaying, err := b.Music.NowPlaying(guildID)
+		if err != nil || nowPlaying.Track.Encoded != track.Encoded {
+			return
+		}
+		current := result.LineAt(positionDuration(nowPlaying.Position))
+		if current == line {
+			continue
+		}
+		line = current
+		if _, err = b.Client.Rest().UpdateInteractionResponse(applicationID, token, discord.MessageUpdate{
+			Content: common.Ptr(syncedLyricsContent(track, result, line)),
+		}); err != nil {
+			b.logger.Errorf("error updating synced lyrics in guild %s: %v", guildID, err)
+			return
+		}
+	}
+}
+
+// syncedLyricsContent renders the synced lines around line, highlighting the current one.
+func syncedLyricsContent(track lavalink.Track, result *lyrics.Lyrics, line int) string {
+	var content strings.Builder
+	fmt.Fprintf(&content, "Lyrics for `%s` from %s, following playback:\n", track.Info.Title, result.Provider)
+	first := max(line-lyricsLinesBefore, 0)
+	last := min(line+lyricsLinesAfter, len(result.Lines)-1)
+	for i := first; i <= last; i++ {
+		text := result.Lines[i].Text
+		if text == "" {
+			text = "♪"
+		}
+		if i == line {
+			fmt.Fprintf(&content, "**%s**\n", text)
+		} else {
+			fmt.Fprintf(&content, "-# %s\n", text)
+		}
+	}
+	return strings.TrimSuffix(content.String(), "\n")
+}
+
+func positionDuration(position lavalink.Duration) time.Duration {
+	return time.Duration(position) * time.Millisecond
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/lyrics"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLyricsProvider map[string]*lyrics.Lyrics
+
+func (fakeLyricsProvider) Name() string { return "fake" }
+
+func (f fakeLyricsProvider) Lyrics(_ context.Context, track lavalink.Track) (*lyrics.Lyrics, error) {
+	if result, ok := f[track.Encoded]; ok {
+		return result, nil
+	}
+	return nil, lyrics.ErrNotFound
+}
+
+// playTrack starts track a and waits until the player reports it.
+func (f *gatewayFixture) playTrack(t *testing.T) {
+	t.Helper()
+	f.server.AddTrack("https://example.com/a", lavalink.Track{Encoded: "a", Info: lavalink.TrackInfo{Title: "a", Length: lavalink.Minute}})
+	events, cancel := f.bot.playerEvents.Subscribe()
+	defer cancel()
+	_, err := f.bot.Music.Play(context.Background(), testGuildID, testChannelID, testUserID, "https://example.com/a", "")
+	require.NoError(t, err)
+	waitForEvent(t, events, PlayerEventTrackStart)
+}
+
+func Test_Gateway_LyricsFromLavaLyrics(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.server.SetLyrics("a", map[string]any{
+		"sourceName": "youtube",
+		"provider":   "YouTube Music",
+		"text":       "First line\nSecond line",
+		"lines":      []any{},
+	})
+
+	f.replay("lyrics")
+
+	f.discord.AssertGolden("lyrics")
+}
+
+func Test_Gateway_LyricsFallbackProvider(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	require.NoError(t, WithLyricsProvider(fakeLyricsProvider{"a": {Provider: "fake", Text: strings.Repeat("a", 1000) + "\n" + strings.Repeat("b", 1000)}})(f.bot))
+
+	f.replay("lyrics")
+
+	f.discord.AssertGolden("lyrics_fallback")
+}
+
+func Test_Gateway_LyricsNotFound(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.server.SetLyrics("other", map[string]any{})
+
+	f.replay("lyrics")
+
+	f.discord.AssertGolden("lyrics_not_found")
+}
+
+func Test_Gateway_LyricsEmptyText(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.bot.Lyrics = fakeLyricsProvider{"a": {Provider: "fake", Text: " \n"}}
+
+	f.replay("lyrics")
+
+	f.discord.AssertGolden("lyrics_empty_text")
+}
+
+func Test_Gateway_SyncedLyricsFollowPlayback(t *testing.T) {
+	interval, duration := lyricsFollowInterval, lyricsFollowDuration
+	lyricsFollowInterval, lyricsFollowDuration = 10*time.Millisecond, time.Second
+	t.Cleanup(func() { lyricsFollowInterval, lyricsFollowDuration = interval, duration })
+
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.bot.Lyrics = fakeLyricsProvider{"a": {Provider: "fake", Text: "First\nSecond", Lines: []lyrics.Line{
+		{Timestamp: 0, Text: "First"},
+		{Timestamp: 200 * time.Millisecond, Text: "Second"},
+	}}}
+
+	f.replay("lyrics_synced")
+
+	assert.Eventually(t, func() bool {
+		requests := f.discord.Transport.Requests()
+		return len(requests) == 3 && strings.Contains(string(requests[2].Body), "**Second**")
+	}, 2*time.Second, 10*time.Millisecond)
+	requests := f.discord.Transport.Requests()
+	assert.Contains(t, string(requests[1].Body), "**First**")
+}
+
+func Test_SyncedLyricsContent(t *testing.T) {
+	result := &lyrics.Lyrics{Provider: "fake"}
+	for i := range 12 {
+		result.Lines = append(result.Lines, lyrics.Line{Timestamp: time.Duration(i) * time.Second, Text: string(rune('a' + i))})
+	}
+	result.Lines[5].Text = ""
+
+	assert.Equal(t, "Lyrics for `a` from fake, following playback:\n-# d\n-# e\n**♪**\n-# g\n-# h\n-# i\n-# j\n-# k\n-# l", syncedLyricsContent(testTrack("a"), result, 5))
+	assert.Equal(t, "Lyrics for `a` from fake, following playback:\n-# a\n-# b\n-# c\n-# d\n-# e\n-# ♪", syncedLyricsContent(testTrack("a"), result, -1))
+}
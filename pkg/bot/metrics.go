@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"strconv"
+
+	"go-discord-music/pkg/metrics"
+
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	activePlayersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "", "active_players"),
+		"Number of players known to the Lavalink client.",
+		nil, nil,
+	)
+	playingPlayersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "", "playing_players"),
+		"Number of players currently playing a track.",
+		nil, nil,
+	)
+	queuedTracksDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "", "queued_tracks"),
+		"Number of tracks waiting in the queues of all guilds.",
+		nil, nil,
+	)
+	gatewayLatencyDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "gateway", "latency_seconds"),
+		"Latency of the Discord gateway heartbeat per shard.",
+		[]string{"shard"}, nil,
+	)
+	nodeUpDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_up"),
+		"Whether the Lavalink node is connected (1) or not (0).",
+		[]string{"node"}, nil,
+	)
+	nodePlayersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_players"),
+		"Number of players on the Lavalink node.",
+		[]string{"node"}, nil,
+	)
+	nodePlayingPlayersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_playing_players"),
+		"Number of playing players on the Lavalink node.",
+		[]string{"node"}, nil,
+	)
+	nodeUptimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_uptime_seconds"),
+		"Uptime of the Lavalink node.",
+		[]string{"node"}, nil,
+	)
+	nodeMemoryUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_memory_used_bytes"),
+		"Memory used by the Lavalink node.",
+		[]string{"node"}, nil,
+	)
+	nodeSystemLoadDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_system_load"),
+		"System load reported by the Lavalink node.",
+		[]string{"node"}, nil,
+	)
+	nodeLavalinkLoadDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_lavalink_load"),
+		"Lavalink process load reported by the Lavalink node.",
+		[]string{"node"}, nil,
+	)
+	nodeFramesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "lavalink", "node_frames"),
+		"Audio frame statistics for the last minute reported by the Lavalink node, by kind (sent, nulled, deficit).",
+		[]string{"node", "kind"}, nil,
+	)
+)
+
+// botCollector exports metrics which are read from the live bot state at scrape time.
+type botCollector struct {
+	bot *Bot
+}
+
+func (c *botCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- activePlayersDesc
+	ch <- playingPlayersDesc
+	ch <- queuedTracksDesc
+	ch <- gatewayLatencyDesc
+	ch <- nodeUpDesc
+	ch <- nodePlayersDesc
+	ch <- nodePlayingPlayersDesc
+	ch <- nodeUptimeDesc
+	ch <- nodeMemoryUsedDesc
+	ch <- nodeSystemLoadDesc
+	ch <- nodeLavalinkLoadDesc
+	ch <- nodeFramesDesc
+}
+
+func (c *botCollector) Collect(ch chan<- prometheus.Metric) {
+	b := c.bot
+
+	var active, playing int
+	b.Lavalink.ForPlayers(func(player disgolink.Player) {
+		active++
+		if player.Track() != nil && !player.Paused() {
+			playing++
+		}
+	})
+	ch <- prometheus.MustNewConstMetric(activePlayersDesc, prometheus.GaugeValue, float64(active))
+	ch <- prometheus.MustNewConstMetric(playingPlayersDesc, prometheus.GaugeValue, float64(playing))
+
+	var queued int
+	b.Queues.ForEach(func(_ snowflake.ID, queue *Queue) {
+		queued += queue.Len()
+	})
+	ch <- prometheus.MustNewConstMetric(queuedTracksDesc, prometheus.GaugeValue, float64(queued))
+
+	for shardID, gw := range b.gateways() {
+		ch <- prometheus.MustNewConstMetric(gatewayLatencyDesc, prometheus.GaugeValue, gw.Latency().Seconds(), strconv.Itoa(shardID))
+	}
+
+	b.Lavalink.ForNodes(func(node disgolink.Node) {
+		name := node.Config().Name
+		up := 0.0
+		if node.Status() == disgolink.StatusConnected {
+			up = 1
+		}
+		stats := node.Stats()
+		ch <- prometheus.MustNewConstMetric(nodeUpDesc, prometheus.GaugeValue, up, name)
+		ch <- prometheus.MustNewConstMetric(nodePlayersDesc, prometheus.GaugeValue, float64(stats.Players), name)
+		ch <- prometheus.MustNewConstMetric(nodePlayingPlayersDesc, prometheus.GaugeValue, float64(stats.PlayingPlayers), name)
+		ch <- prometheus.MustNewConstMetric(nodeUptimeDesc, prometheus.GaugeValue, float64(stats.Uptime.Milliseconds())/1000, name)
+		ch <- prometheus.MustNewConstMetric(nodeMemoryUsedDesc, prometheus.GaugeValue, float64(stats.Memory.Used), name)
+		ch <- prometheus.MustNewConstMetric(nodeSystemLoadDesc, prometheus.GaugeValue, stats.CPU.SystemLoad, name)
+		ch <- prometheus.MustNewConstMetric(nodeLavalinkLoadDesc, prometheus.GaugeValue, stats.CPU.LavalinkLoad, name)
+		if stats.FrameStats != nil {
+			ch <- prometheus.MustNewConstMetric(nodeFramesDesc, prometheus.GaugeValue, float64(stats.FrameStats.Sent), name, "sent")
+			ch <- prometheus.MustNewConstMetric(nodeFramesDesc, prometheus.GaugeValue, float64(stats.FrameStats.Nulled), name, "nulled")
+			ch <- prometheus.MustNewConstMetric(nodeFramesDesc, prometheus.GaugeValue, float64(stats.FrameStats.Deficit), name, "deficit")
+		}
+	})
+}
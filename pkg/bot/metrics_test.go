@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/metrics"
+
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Gateway_BotCollector(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.bot.Queues.Get(testGuildID).Add(lavalink.Track{Encoded: "b"}, lavalink.Track{Encoded: "c"})
+	f.bot.Queues.Get(testGuildID + 1).Add(lavalink.Track{Encoded: "d"})
+
+	// disgolink stores node stats without synchronisation, the listener runs once they are stored.
+	stats := make(chan struct{})
+	statsListener := disgolink.NewListenerFunc(func(_ disgolink.Player, _ lavalink.StatsMessage) {
+		close(stats)
+	})
+	f.bot.Lavalink.AddListeners(statsListener)
+	defer f.bot.Lavalink.RemoveListeners(statsListener)
+	require.NoError(t, f.server.SetStats(lavalink.Stats{
+		Uptime:     90 * lavalink.Second,
+		Memory:     lavalink.Memory{Used: 1024},
+		CPU:        lavalink.CPU{Cores: 4, SystemLoad: 0.5, LavalinkLoad: 0.25},
+		FrameStats: &lavalink.FrameStats{Sent: 3000, Nulled: 2, Deficit: 1},
+	}))
+	select {
+	case <-stats:
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "timed out waiting for the node stats")
+	}
+
+	expected := `
+# HELP discord_music_active_players Number of players known to the Lavalink client.
+# TYPE discord_music_active_players gauge
+discord_music_active_players 1
+# HELP discord_music_playing_players Number of players currently playing a track.
+# TYPE discord_music_playing_players gauge
+discord_music_playing_players 1
+# HELP discord_music_queued_tracks Number of tracks waiting in the queues of all guilds.
+# TYPE discord_music_queued_tracks gauge
+discord_music_queued_tracks 3
+# HELP discord_music_gateway_latency_seconds Latency of the Discord gateway heartbeat per shard.
+# TYPE discord_music_gateway_latency_seconds gauge
+discord_music_gateway_latency_seconds{shard="0"} 0
+# HELP discord_music_lavalink_node_up Whether the Lavalink node is connected (1) or not (0).
+# TYPE discord_music_lavalink_node_up gauge
+discord_music_lavalink_node_up{node="test"} 1
+# HELP discord_music_lavalink_node_players Number of players on the Lavalink node.
+# TYPE discord_music_lavalink_node_players gauge
+discord_music_lavalink_node_players{node="test"} 1
+# HELP discord_music_lavalink_node_playing_players Number of playing players on the Lavalink node.
+# TYPE discord_music_lavalink_node_playing_players gauge
+discord_music_lavalink_node_playing_players{node="test"} 1
+# HELP discord_music_lavalink_node_uptime_seconds Uptime of the Lavalink node.
+# TYPE discord_music_lavalink_node_uptime_seconds gauge
+discord_music_lavalink_node_uptime_seconds{node="test"} 90
+# HELP discord_music_lavalink_node_memory_used_bytes Memory used by the Lavalink node.
+# TYPE discord_music_lavalink_node_memory_used_bytes gauge
+discord_music_lavalink_node_memory_used_bytes{node="test"} 1024
+# HELP discord_music_lavalink_node_system_load System load reported by the Lavalink node.
+# TYPE discord_music_lavalink_node_system_load gauge
+discord_music_lavalink_node_system_load{node="test"} 0.5
+# HELP discord_music_lavalink_node_lavalink_load Lavalink process load reported by the Lavalink node.
+# TYPE discord_music_lavalink_node_lavalink_load gauge
+discord_music_lavalink_node_lavalink_load{node="test"} 0.25
+# HELP discord_music_lavalink_node_frames Audio frame statistics for the last minute reported by the Lavalink node, by kind (sent, nulled, deficit).
+# TYPE discord_music_lavalink_node_frames gauge
+discord_music_lavalink_node_frames{kind="deficit",node="test"} 1
+discord_music_lavalink_node_frames{kind="nulled",node="test"} 2
+discord_music_lavalink_node_frames{kind="sent",node="test"} 3000
+`
+	assert.NoError(t, testutil.CollectAndCompare(&botCollector{bot: f.bot}, strings.NewReader(expected)))
+}
+
+func Test_Gateway_CommandMetrics(t *testing.T) {
+	f := newGatewayFixture(t)
+	unknown := metrics.CommandsHandled.WithLabelValues("nope", metrics.OutcomeUnknown)
+	before := testutil.ToFloat64(unknown)
+
+	f.replay("unknown_command")
+
+	assert.Equal(t, before+1, testutil.ToFloat64(unknown))
+}
+
+func Test_Gateway_TrackMetrics(t *testing.T) {
+	f := newGatewayFixture(t)
+	starts := testutil.ToFloat64(metrics.TrackStarts)
+	finished := metrics.TrackEnds.WithLabelValues(string(lavalink.TrackEndReasonFinished))
+	ends := testutil.ToFloat64(finished)
+
+	f.playTrack(t)
+	events, cancel := f.bot.playerEvents.Subscribe()
+	defer cancel()
+	require.NoError(t, f.server.FinishTrack(testGuildID, lavalink.TrackEndReasonFinished))
+	waitForEvent(t, events, PlayerEventTrackEnd)
+
+	assert.Equal(t, starts+1, testutil.ToFloat64(metrics.TrackStarts))
+	assert.Equal(t, ends+1, testutil.ToFloat64(finished))
+}
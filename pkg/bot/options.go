@@ -3,8 +3,13 @@ package bot
 import (
 	"context"
 	"fmt"
+	"maps"
 	"time"
 
+	"go-discord-music/pkg/lyrics"
+	"go-discord-music/pkg/ratelimit"
+	"go-discord-music/pkg/store"
+
 	"github.com/disgoorg/disgolink/v3/disgolink"
 )
 
@@ -28,7 +33,7 @@ func WithLavaLinkNode(node disgolink.NodeConfig) Option {
 		b.logger.Debugf("Using Lavalink node configuration: %#v", node)
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		_, err := b.Lavalink.AddNode(ctx, node)
+		_, err := b.addNode(ctx, node)
 		if err != nil {
 			return fmt.Errorf("error adding lavalink node %s: %w", node.Name, err)
 		}
@@ -42,7 +47,7 @@ func WithLavaLinkDefault() Option {
 			return fmt.Errorf("lavalink client is required when using WithLavaLinkDefault option")
 		}
 		b.logger.Debugf("Using default Lavalink node configuration: %s", defaultLavalinkNode.Name)
-		_, err := b.Lavalink.AddNode(context.Background(), defaultLavalinkNode)
+		_, err := b.addNode(context.Background(), defaultLavalinkNode)
 		return err
 	}
 }
@@ -53,3 +58,141 @@ func WithIdleTimeout(timout time.Duration) Option {
 		return nil
 	}
 }
+
+// WithAloneTimeout sets how long the bot stays in a voice channel without listeners. 0 disables the timeout.
+func WithAloneTimeout(timeout time.Duration) Option {
+	return func(b *Bot) error {
+		b.AloneTimeout = timeout
+		return nil
+	}
+}
+
+// WithPausedTimeout sets how long a player may stay paused before the bot leaves. 0 disables the timeout.
+func WithPausedTimeout(timeout time.Duration) Option {
+	return func(b *Bot) error {
+		b.PausedTimeout = timeout
+		return nil
+	}
+}
+
+// WithAutoPause pauses playback when all listeners leave the voice channel and resumes it when one returns.
+func WithAutoPause(enabled bool) Option {
+	return func(b *Bot) error {
+		if b.Music == nil {
+			return fmt.Errorf("music service is required when using WithAutoPause option")
+		}
+		b.Music.AutoPause = enabled
+		return nil
+	}
+}
+
+// WithSameChannel requires users to be in the bot's voice channel to control playback. It is enabled by default.
+func WithSameChannel(enabled bool) Option {
+	return func(b *Bot) error {
+		if b.Music == nil {
+			return fmt.Errorf("music service is required when using WithSameChannel option")
+		}
+		b.Music.SameChannel = enabled
+		return nil
+	}
+}
+
+// WithStageTopic sets the topic of the stage the bot plays in to the current track, starting the stage if needed.
+func WithStageTopic(enabled bool) Option {
+	return func(b *Bot) error {
+		b.StageTopic = enabled
+		return nil
+	}
+}
+
+// WithVoiceStatus sets the status of the bot's voice channel to the current track and clears it when playback stops.
+func WithVoiceStatus(enabled bool) Option {
+	return func(b *Bot) error {
+		b.VoiceStatus = enabled
+		return nil
+	}
+}
+
+// WithPresence shows what the bot plays as its activity, rendered from the templates used while it plays in a single
+// server and in several, see PresenceTemplates. Without this option, or when both are empty, the presence is left
+// alone.
+func WithPresence(track string, servers string) Option {
+	return func(b *Bot) error {
+		if track == "" && servers == "" {
+			b.Presence = nil
+			return nil
+		}
+		presence, err := ParsePresenceTemplates(track, servers)
+		if err != nil {
+			return err
+		}
+		b.Presence = presence
+		return nil
+	}
+}
+
+// WithSharding connects to Discord with the given shards instead of a single gateway, see Sharding.
+func WithSharding(sharding Sharding) Option {
+	return func(b *Bot) error {
+		if err := sharding.validate(); err != nil {
+			return err
+		}
+		b.Sharding = &sharding
+		return nil
+	}
+}
+
+// WithDataDir persists settings, such as 24/7 mode, in dir. Settings are kept in memory when dir is empty.
+func WithDataDir(dir string) Option {
+	return func(b *Bot) error {
+		if dir == "" {
+			return nil
+		}
+		s, err := store.Open(dir)
+		if err != nil {
+			return fmt.Errorf("error opening data directory %s: %w", dir, err)
+		}
+		b.Store = s
+		return nil
+	}
+}
+
+// WithLyricsProvider adds a lyrics provider which is used when the Lavalink node has no lyrics for a track.
+func WithLyricsProvider(provider lyrics.Provider) Option {
+	return func(b *Bot) error {
+		b.Lyrics = lyrics.Chain(b.Lyrics, provider)
+		return nil
+	}
+}
+
+// WithHTTPAddress enables the HTTP listener serving metrics and health probes on the given address.
+func WithHTTPAddress(address string) Option {
+	return func(b *Bot) error {
+		b.HTTPAddress = address
+		return nil
+	}
+}
+
+// WithAPI enables the authenticated control API on the given address. Requests must present token as a bearer token.
+func WithAPI(address string, token string) Option {
+	return func(b *Bot) error {
+		if address == "" {
+			return nil
+		}
+		if token == "" {
+			return fmt.Errorf("an API token is required when the control API is enabled")
+		}
+		b.APIAddress = address
+		b.apiToken = token
+		return nil
+	}
+}
+
+// WithRateLimits overrides the per user command rate limits by command name. The ratelimit.Default entry applies to
+// commands without their own limit.
+func WithRateLimits(limits ratelimit.Limits) Option {
+	return func(b *Bot) error {
+		maps.Copy(b.RateLimits, limits)
+		return nil
+	}
+}
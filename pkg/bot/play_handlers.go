@@ -2,70 +2,62 @@ package bot
 
 import (
 	"context"
-	"time"
+
+	"go-discord-music/pkg/metrics"
 
 	"github.com/disgoorg/disgolink/v3/disgolink"
 	"github.com/disgoorg/disgolink/v3/lavalink"
 )
 
-func (b *Bot) onPlayerPause(_ disgolink.Player, event lavalink.PlayerPauseEvent) {
-	b.logger.Infof("player paused, %#v", event)
-}
-
-func (b *Bot) onPlayerResume(_ disgolink.Player, event lavalink.PlayerResumeEvent) {
-	b.logger.Infof("player resumed, %#v", event)
-}
-
 func (b *Bot) onTrackStart(_ disgolink.Player, event lavalink.TrackStartEvent) {
 	b.logger.Infof("track started, guild: %s, track: %#v", event.GuildID(), event.Track)
-	if !b.idleTimes[event.GuildID()].IsZero() {
-		b.logger.Infof("resetting idle timeout for guild %s", event.GuildID())
-		delete(b.idleTimes, event.GuildID())
-	}
+	metrics.TrackStarts.Inc()
+	b.updateStageTopic(event.GuildID(), event.Track)
+	b.updateVoiceStatus(event.GuildID(), &event.Track)
+	b.updatePresence()
+	b.playerEvents.Publish(PlayerEvent{Type: PlayerEventTrackStart, GuildID: event.GuildID(), Track: &event.Track})
+	b.clearIdle(event.GuildID(), idleReasonNoTrack)
 }
 
-func (b *Bot) onTrackEnd(player disgolink.Player, event lavalink.TrackEndEvent) {
-	if !event.Reason.MayStartNext() {
-		return
-	}
+func (b *Bot) onTrackEnd(_ disgolink.Player, event lavalink.TrackEndEvent) {
+	metrics.TrackEnds.WithLabelValues(string(event.Reason)).Inc()
+	b.playerEvents.Publish(PlayerEvent{Type: PlayerEventTrackEnd, GuildID: event.GuildID(), Track: &event.Track, Reason: string(event.Reason)})
+	b.updatePresence()
 
-	queue := b.Queues.Get(event.GuildID())
-	var (
-		nextTrack lavalink.Track
-		ok        bool
-	)
-	switch queue.Type {
-	case QueueTypeNormal:
-		nextTrack, ok = queue.Next()
-
-	case QueueTypeRepeatTrack:
-		nextTrack = event.Track
-
-	case QueueTypeRepeatQueue:
-		queue.Add(event.Track)
-		nextTrack, ok = queue.Next()
-	}
-
-	if !ok {
-		b.idleTimes[event.GuildID()] = time.Now().Add(b.IdleTimeout)
-		b.logger.Infof("no next track available, setting idle timeout for guild %s to %d seconds", event.GuildID(), b.IdleTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), eventTimeout)
+	defer cancel()
+	nextTrack, err := b.Music.TrackEnded(ctx, event.GuildID(), event.Track, event.Reason)
+	if err != nil {
+		b.logger.Errorf("error updating player track: %v", err)
 		return
 	}
-	if err := player.Update(context.TODO(), lavalink.WithTrack(nextTrack)); err != nil {
-		b.logger.Errorf("error updating player track: %v", err)
+	if nextTrack == nil && event.Reason != lavalink.TrackEndReasonReplaced {
+		b.updateVoiceStatus(event.GuildID(), nil)
+	}
+	if nextTrack == nil && event.Reason.MayStartNext() {
+		if b.alwaysOn(event.GuildID()) != nil {
+			b.playFallback(ctx, event.GuildID())
+			return
+		}
+		b.logger.Infof("no next track available, setting idle timeout for guild %s to %s", event.GuildID(), b.IdleTimeout)
 	}
 }
 
 func (b *Bot) onTrackException(_ disgolink.Player, event lavalink.TrackExceptionEvent) {
 	b.logger.Errorf("track exception: %#v", event)
+	metrics.TrackExceptions.WithLabelValues(string(event.Exception.Severity)).Inc()
+	b.playerEvents.Publish(PlayerEvent{Type: PlayerEventTrackException, GuildID: event.GuildID(), Track: &event.Track, Reason: event.Exception.Message})
 }
 
 func (b *Bot) onTrackStuck(_ disgolink.Player, event lavalink.TrackStuckEvent) {
 	b.logger.Warnf("track stuck: %#v", event)
+	metrics.TrackStuck.Inc()
+	b.playerEvents.Publish(PlayerEvent{Type: PlayerEventTrackStuck, GuildID: event.GuildID(), Track: &event.Track})
 }
 
 func (b *Bot) onWebSocketClosed(_ disgolink.Player, event lavalink.WebSocketClosedEvent) {
 	b.logger.Warnf("websocket closed: %#v", event)
+	b.playerEvents.Publish(PlayerEvent{Type: PlayerEventWebSocketClosed, GuildID: event.GuildID(), Reason: event.Reason})
 }
 
 func (b *Bot) onUnknownEvent(_ disgolink.Player, e lavalink.UnknownEvent) {
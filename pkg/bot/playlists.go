@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"go-discord-music/pkg/store"
+
+	"github.com/Cyb3r-Jak3/common/v5"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+const (
+	// maxPlaylists is the number of playlists a user or guild can save.
+	maxPlaylists = 25
+	// maxPlaylistTracks is the number of tracks a single playlist can hold.
+	maxPlaylistTracks = 500
+)
+
+// playlistScope selects whether a playlist belongs to the user or is shared with the whole guild.
+type playlistScope string
+
+const (
+	playlistScopePersonal playlistScope = "personal"
+	playlistScopeServer   playlistScope = "server"
+)
+
+// playlistCommand is the target of a /playlist subcommand.
+type playlistCommand struct {
+	event   *events.ApplicationCommandInteractionCreate
+	data    discord.SlashCommandInteractionData
+	guildID snowflake.ID
+	userID  snowflake.ID
+	scope   playlistScope
+	name    string
+}
+
+// playlists returns the saved playlists of the scope.
+func (b *Bot) playlists(scope playlistScope, guildID snowflake.ID, userID snowflake.ID) store.Playlists {
+	if scope == playlistScopeServer {
+		return b.Store.Guild(guildID).Playlists
+	}
+	return b.Store.User(userID).Playlists
+}
+
+// updatePlaylists applies fn to the saved playlists of the scope and persists the result. Nothing is changed when fn
+// returns an error.
+func (b *Bot) updatePlaylists(scope playlistScope, guildID snowflake.ID, userID snowflake.ID, fn func(playlists store.Playlists) error) error {
+	if scope == playlistScopeServer {
+		return b.Store.UpdateGuild(guildID, func(settings *store.GuildSettings) error {
+			return fn(settings.Playlists)
+		})
+	}
+	return b.Store.UpdateUser(userID, func(settings *store.UserSettings) error {
+		return fn(settings.Playlists)
+	})
+}
+
+func (b *Bot) playlist(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	cmd := playlistCommand{
+		event:   event,
+		data:    data,
+		guildID: *event.GuildID(),
+		userID:  event.User().ID,
+		scope:   playlistScopePersonal,
+		name:    data.String("name"),
+	}
+	if scope, ok := data.OptString("scope"); ok {
+		cmd.scope = playlistScope(scope)
+	}
+	if data.SubCommandName == nil {
+		return userErrorf("Unknown playlist command")
+	}
+	switch *data.SubCommandName {
+	case "save":
+		return b.savePlaylist(ctx, cmd)
+	case "append":
+		return b.appendPlaylist(ctx, cmd)
+	case "load":
+		return b.loadPlaylist(ctx, cmd)
+	case "list":
+		return b.listPlaylists(ctx, cmd)
+	case "delete":
+		return b.deletePlaylist(ctx, cmd)
+	default:
+		return userErrorf("Unknown playlist command: `%s`", *data.SubCommandName)
+	}
+}
+
+// canEditPlaylist reports whether the user may overwrite or delete the playlist. Server playlists
+// can be edited by whoever saved them and by members with the Manage Server permission.
+func (cmd playlistCommand) canEditPlaylist(playlist store.Playlist) bool {
+	if cmd.scope != playlistScopeServer || playlist.CreatedBy == cmd.userID {
+		return true
+	}
+	member := cmd.event.Member()
+	return member != nil && member.Permissions.Has(discord.PermissionManageGuild)
+}
+
+// checkSave returns a UserError when the playlist called key may not be saved to playlists.
+func (cmd playlistCommand) checkSave(playlists store.Playlists, key string) error {
+	existing, exists := playlists[key]
+	switch {
+	case key == "":
+		return userErrorf("Playlist names cannot be empty")
+	case exists && !cmd.canEditPlaylist(existing):
+		return userErrorf("Server playlist `%s` was saved by someone else and cannot be overwritten", existing.Name)
+	case !exists && len(playlists) >= maxPlaylists:
+		return userErrorf("You cannot save more than `%d` %s playlists, delete one first", maxPlaylists, cmd.scope)
+	}
+	return nil
+}
+
+// resolvePlaylistTracks returns the tracks of the identifier option, or the current track and queue without one.
+func (b *Bot) resolvePlaylistTracks(ctx context.Context, cmd playlistCommand) ([]lavalink.Track, error) {
+	if identifier, ok := cmd.data.OptString("identifier"); ok {
+		return b.Music.Resolve(ctx, identifier, "")
+	}
+	tracks := b.Music.Tracks(cmd.guildID)
+	if len(tracks) == 0 {
+		return nil, ErrNoTracks
+	}
+	return tracks, nil
+}
+
+// respondPlaylist updates the deferred response of a playlist command.
+func (b *Bot) respondPlaylist(cmd playlistCommand, content string) error {
+	_, err := b.Client.Rest().UpdateInteractionResponse(cmd.event.ApplicationID(), cmd.event.Token(), discord.MessageUpdate{
+		Content: common.Ptr(content),
+	})
+	return err
+}
+
+func (b *Bot) savePlaylist(ctx context.Context, cmd playlistCommand) error {
+	key := store.PlaylistKey(cmd.name)
+	// Checked before loading the tracks to fail fast, and again when saving as the playlists may have changed meanwhile.
+	if err := cmd.checkSave(b.playlists(cmd.scope, cmd.guildID, cmd.userID), key); err != nil {
+		return err
+	}
+	if err := cmd.event.DeferCreateMessage(false); err != nil {
+		return err
+	}
+
+	tracks, err := b.resolvePlaylistTracks(ctx, cmd)
+	if err != nil {
+		return serviceError("loading tracks", err)
+	}
+	if len(tracks) > maxPlaylistTracks {
+		tracks = tracks[:maxPlaylistTracks]
+	}
+
+	playlist := store.Playlist{Name: cmd.name, CreatedBy: cmd.userID, Tracks: tracks}
+	if err = b.updatePlaylists(cmd.scope, cmd.guildID, cmd.userID, func(playlists store.Playlists) error {
+		if err := cmd.checkSave(playlists, key); err != nil {
+			return err
+		}
+		playlists[key] = playlist
+		return nil
+	}); err != nil {
+		return serviceError("saving the playlist", err)
+	}
+	return b.respondPlaylist(cmd, fmt.Sprintf("Saved %s playlist `%s` with `%d` tracks", cmd.scope, cmd.name, len(tracks)))
+}
+
+func (b *Bot) appendPlaylist(ctx context.Context, cmd playlistCommand) error {
+	key := store.PlaylistKey(cmd.name)
+	existing, ok := b.playlists(cmd.scope, cmd.guildID, cmd.userID)[key]
+	if !ok {
+		return userErrorf("No %s playlist named `%s`, create it with `/playlist save`", cmd.scope, cmd.name)
+	}
+	if err := cmd.event.DeferCreateMessage(false); err != nil {
+		return err
+	}
+
+	tracks, err := b.resolvePlaylistTracks(ctx, cmd)
+	if err != nil {
+		return serviceError("loading tracks", err)
+	}
+	var added, total int
+	if err = b.updatePlaylists(cmd.scope, cmd.guildID, cmd.userID, func(playlists store.Playlists) error {
+		playlist, ok := playlists[key]
+		if !ok {
+			return userErrorf("No %s playlist named `%s`, create it with `/playlist save`", cmd.scope, cmd.name)
+		}
+		room := max(maxPlaylistTracks-len(playlist.Tracks), 0)
+		if room == 0 {
+			return userErrorf("Playlist `%s` is full, it can hold up to `%d` tracks", playlist.Name, maxPlaylistTracks)
+		}
+		added = min(len(tracks), room)
+		playlist.Tracks = slices.Concat(playlist.Tracks, tracks[:added])
+		playlists[key] = playlist
+		total = len(playlist.Tracks)
+		return nil
+	}); err != nil {
+		return serviceError("saving the playlist", err)
+	}
+	return b.respondPlaylist(cmd, fmt.Sprintf("Added `%d` tracks to %s playlist `%s`, it now has `%d` tracks", added, cmd.scope, existing.Name, total))
+}
+
+func (b *Bot) loadPlaylist(ctx context.Context, cmd playlistCommand) error {
+	playlist, ok := b.playlists(cmd.scope, cmd.guildID, cmd.userID)[store.PlaylistKey(cmd.name)]
+	if !ok {
+		return userErrorf("No %s playlist named `%s`", cmd.scope, cmd.name)
+	}
+	channelID, err := b.Music.JoinableChannel(cmd.guildID, cmd.userID)
+	if err != nil {
+		return serviceError("joining your voice channel", err)
+	}
+	if err = cmd.event.DeferCreateMessage(false); err != nil {
+		return err
+	}
+
+	result, err := b.Music.PlayTracks(ctx, cmd.guildID, channelID, cmd.userID, playlist.Tracks)
+	switch {
+	case err != nil:
+		return serviceError("playing the playlist", err)
+	case result.Queued:
+		return b.respondPlaylist(cmd, fmt.Sprintf("Queued playlist: `%s` with `%d` tracks", playlist.Name, len(result.Tracks))+rejectionNote(result.Rejected))
+	default:
+		return b.respondPlaylist(cmd, fmt.Sprintf("Loaded playlist: `%s` with `%d` tracks", playlist.Name, len(result.Tracks))+rejectionNote(result.Rejected))
+	}
+}
+
+func (b *Bot) listPlaylists(_ context.Context, cmd playlistCommand) error {
+	playlists := b.playlists(cmd.scope, cmd.guildID, cmd.userID)
+	if len(playlists) == 0 {
+		return cmd.event.CreateMessage(discord.MessageCreate{
+			Content: fmt.Sprintf("No %s playlists saved", cmd.scope),
+		})
+	}
+
+	keys := make([]string, 0, len(playlists))
+	for key := range playlists {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	content := fmt.Sprintf("Saved %s playlists:\n", cmd.scope)
+	for _, key := range keys {
+		playlist := playlists[key]
+		content += fmt.Sprintf("- `%s`: `%d` tracks\n", playlist.Name, len(playlist.Tracks))
+	}
+	return cmd.event.CreateMessage(discord.MessageCreate{
+		Content: content,
+	})
+}
+
+func (b *Bot) deletePlaylist(_ context.Context, cmd playlistCommand) error {
+	key := store.PlaylistKey(cmd.name)
+	var name string
+	if err := b.updatePlaylists(cmd.scope, cmd.guildID, cmd.userID, func(playlists store.Playlists) error {
+		playlist, ok := playlists[key]
+		if !ok {
+			return userErrorf("No %s playlist named `%s`", cmd.scope, cmd.name)
+		}
+		if !cmd.canEditPlaylist(playlist) {
+			return userErrorf("Server playlist `%s` was saved by someone else and cannot be deleted", playlist.Name)
+		}
+		name = playlist.Name
+		delete(playlists, key)
+		return nil
+	}); err != nil {
+		return serviceError("deleting the playlist", err)
+	}
+	return cmd.event.CreateMessage(discord.MessageCreate{
+		Content: fmt.Sprintf("Deleted %s playlist `%s`", cmd.scope, name),
+	})
+}
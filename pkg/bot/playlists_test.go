@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"go-discord-music/pkg/store"
+
+	"github.com/Cyb3r-Jak3/common/v5"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func playlistTrack(encoded string) lavalink.Track {
+	return lavalink.Track{Encoded: encoded, Info: lavalink.TrackInfo{Title: encoded, URI: common.Ptr("https://example.com/" + encoded)}}
+}
+
+func Test_Gateway_PlaylistSaveAndLoad(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.server.SetLoadResult("https://example.com/list", lavalink.LoadResult{Data: lavalink.Playlist{
+		Info:   lavalink.PlaylistInfo{Name: "list"},
+		Tracks: []lavalink.Track{playlistTrack("a"), playlistTrack("b")},
+	}})
+
+	f.replay("playlist_save")
+	f.replay("playlist_load")
+
+	f.discord.AssertGolden("playlist_save_load")
+	playlist := f.bot.Store.Guild(testGuildID).Playlists["road trip"]
+	assert.Equal(t, "Road Trip", playlist.Name)
+	assert.Equal(t, testUserID, playlist.CreatedBy)
+	assert.Equal(t, "a", f.server.CurrentTrack(testGuildID))
+	queued := f.bot.Queues.Get(testGuildID).Snapshot()
+	require.Len(t, queued, 1)
+	assert.Equal(t, "b", queued[0].Encoded)
+}
+
+func Test_Gateway_PlaylistDeleteRequiresOwner(t *testing.T) {
+	f := newGatewayFixture(t)
+	require.NoError(t, f.bot.Store.UpdateGuild(testGuildID, func(settings *store.GuildSettings) error {
+		settings.Playlists["road trip"] = store.Playlist{Name: "Road Trip", CreatedBy: testUserID, Tracks: []lavalink.Track{playlistTrack("a")}}
+		return nil
+	}))
+
+	f.replay("playlist_delete_other")
+
+	f.discord.AssertGolden("playlist_delete_other")
+	assert.Contains(t, f.bot.Store.Guild(testGuildID).Playlists, "road trip")
+}
+
+// hookLoader runs before ahead of every track load.
+type hookLoader struct {
+	TrackLoader
+	before func()
+}
+
+func (l hookLoader) LoadTracks(ctx context.Context, identifier string) (*lavalink.LoadResult, error) {
+	l.before()
+	return l.TrackLoader.LoadTracks(ctx, identifier)
+}
+
+func Test_Gateway_PlaylistSaveRechecksOwner(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.server.SetLoadResult("https://example.com/list", lavalink.LoadResult{Data: lavalink.Playlist{
+		Info:   lavalink.PlaylistInfo{Name: "list"},
+		Tracks: []lavalink.Track{playlistTrack("a"), playlistTrack("b")},
+	}})
+	other := store.Playlist{Name: "Road Trip", CreatedBy: testUserID + 1, Tracks: []lavalink.Track{playlistTrack("c")}}
+	// Someone else saves the playlist while the tracks are loading.
+	f.bot.Music.loader = hookLoader{TrackLoader: f.bot.Music.loader, before: func() {
+		require.NoError(t, f.bot.Store.UpdateGuild(testGuildID, func(settings *store.GuildSettings) error {
+			settings.Playlists["road trip"] = other
+			return nil
+		}))
+	}}
+
+	f.replay("playlist_save")
+
+	f.discord.AssertGolden("playlist_save_recheck")
+	assert.Equal(t, other, f.bot.Store.Guild(testGuildID).Playlists["road trip"])
+}
+
+func Test_Integration_PlayTracksRefreshesStaleTracks(t *testing.T) {
+	f := newIntegrationFixture(t)
+	f.server.AddTrack("https://example.com/a", lavalink.Track{Encoded: "a-v2", Info: lavalink.TrackInfo{Title: "a"}})
+	events, cancel := f.bot.playerEvents.Subscribe()
+	defer cancel()
+
+	result, err := f.bot.Music.PlayTracks(context.Background(), testGuildID, testChannelID, testUserID, []lavalink.Track{playlistTrack("a")})
+
+	require.NoError(t, err)
+	assert.Equal(t, "a-v2", result.Track.Encoded)
+	waitForEvent(t, events, PlayerEventTrackStart)
+	assert.Equal(t, "a-v2", f.server.CurrentTrack(testGuildID))
+}
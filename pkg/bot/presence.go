@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"go-discord-music/pkg/ratelimit"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+)
+
+const (
+	// DefaultTrackPresence is shown as "Listening to ..." while the bot plays in a single server.
+	DefaultTrackPresence = "{{.Title}}{{with .Author}} by {{.}}{{end}}"
+	// DefaultServersPresence is shown as "Playing ..." while the bot plays in several servers.
+	DefaultServersPresence = "in {{.Players}} servers"
+)
+
+// defaultPresence renders DefaultTrackPresence and DefaultServersPresence.
+var defaultPresence = &PresenceTemplates{
+	Track:   template.Must(template.New("track").Parse(DefaultTrackPresence)),
+	Servers: template.Must(template.New("servers").Parse(DefaultServersPresence)),
+}
+
+// presenceLimit limits how often the presence is updated, Discord drops presence updates sent too often.
+var presenceLimit = ratelimit.Limit{Burst: 2, Per: 20 * time.Second}
+
+// presenceMaxLength is the longest activity name shown by Discord.
+const presenceMaxLength = 128
+
+// PresenceTemplates render the bot's activity from a PresenceData. A template rendering to an empty string shows
+// no activity.
+type PresenceTemplates struct {
+	// Track is shown as "Listening to ..." while the bot plays in a single server.
+	Track *template.Template
+	// Servers is shown as "Playing ..." while the bot plays in several servers.
+	Servers *template.Template
+}
+
+// PresenceData is passed to the presence templates.
+type PresenceData struct {
+	// Title, Author and URI describe the track, they are only set while the bot plays in a single server.
+	Title  string
+	Author string
+	URI    string
+	// Players is the number of servers the bot plays in.
+	Players int
+}
+
+// ParsePresenceTemplates parses the templates of the activity while playing in a single server and in several.
+func ParsePresenceTemplates(track string, servers string) (*PresenceTemplates, error) {
+	trackTemplate, err := template.New("track").Parse(track)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing track presence template: %w", err)
+	}
+	serversTemplate, err := template.New("servers").Parse(servers)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing servers presence template: %w", err)
+	}
+	return &PresenceTemplates{Track: trackTemplate, Servers: serversTemplate}, nil
+}
+
+// presenceActivity is a rendered activity, the zero value shows none.
+type presenceActivity struct {
+	Type discord.ActivityType
+	Name string
+}
+
+// activity renders the activity for players servers with a track playing. track is the track playing when there is
+// a single one.
+func (t *PresenceTemplates) activity(players int, track *lavalink.Track) (presenceActivity, error) {
+	if players == 0 {
+		return presenceActivity{}, nil
+	}
+	data := PresenceData{Players: players}
+	tmpl, activityType := t.Servers, discord.ActivityTypeGame
+	if players == 1 && track != nil {
+		data.Title, data.Author = track.Info.Title, track.Info.Author
+		if track.Info.URI != nil {
+			data.URI = *track.Info.URI
+		}
+		tmpl, activityType = t.Track, discord.ActivityTypeListening
+	}
+
+	var name strings.Builder
+	if err := tmpl.Execute(&name, data); err != nil {
+		return presenceActivity{}, fmt.Errorf("error rendering %s presence template: %w", tmpl.Name(), err)
+	}
+	text := strings.TrimSpace(name.String())
+	if text == "" {
+		return presenceActivity{}, nil
+	}
+	if runes := []rune(text); len(runes) > presenceMaxLength {
+		text = string(runes[:presenceMaxLength-1]) + "…"
+	}
+	return presenceActivity{Type: activityType, Name: text}, nil
+}
+
+// updatePresence shows what the bot plays as its activity. It is called whenever a track starts or ends and when a
+// player is removed.
+func (b *Bot) updatePresence() {
+	if b.Presence == nil {
+		return
+	}
+	var (
+		players int
+		track   *lavalink.Track
+	)
+	b.Lavalink.ForPlayers(func(player disgolink.Player) {
+		if player.Track() != nil {
+			players++
+			track = player.Track()
+		}
+	})
+	activity, err := b.Presence.activity(players, track)
+	if err != nil {
+		b.logger.Errorf("error updating presence: %v", err)
+		return
+	}
+	// The presence is set per shard, each shows the activity of the whole process.
+	for _, shardID := range b.shardIDs() {
+		b.presence.set(shardID, activity)
+	}
+}
+
+// sendPresence sets the activity of the bot on a shard.
+func (b *Bot) sendPresence(shardID int, activity presenceActivity) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	opt := func(presence *gateway.MessageDataPresenceUpdate) {
+		if presence.Status == "" {
+			presence.Status = discord.OnlineStatusOnline
+		}
+		presence.Activities = []discord.Activity{}
+		if activity.Name != "" {
+			presence.Activities = []discord.Activity{{Name: activity.Name, Type: activity.Type}}
+		}
+	}
+	if b.Client.HasShardManager() {
+		return b.Client.SetPresenceForShard(ctx, shardID, opt)
+	}
+	return b.Client.SetPresence(ctx, opt)
+}
@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PresenceActivity(t *testing.T) {
+	uri := "https://example.com/a"
+	track := &lavalink.Track{Info: lavalink.TrackInfo{Title: "a", Author: "b", URI: &uri}}
+
+	activity, err := defaultPresence.activity(0, nil)
+	require.NoError(t, err)
+	assert.Zero(t, activity)
+
+	activity, err = defaultPresence.activity(1, track)
+	require.NoError(t, err)
+	assert.Equal(t, presenceActivity{Type: discord.ActivityTypeListening, Name: "a by b"}, activity)
+
+	activity, err = defaultPresence.activity(3, track)
+	require.NoError(t, err)
+	assert.Equal(t, presenceActivity{Type: discord.ActivityTypeGame, Name: "in 3 servers"}, activity)
+
+	custom, err := ParsePresenceTemplates("{{.URI}}", "")
+	require.NoError(t, err)
+	activity, err = custom.activity(1, track)
+	require.NoError(t, err)
+	assert.Equal(t, uri, activity.Name)
+	activity, err = custom.activity(2, track)
+	require.NoError(t, err)
+	assert.Zero(t, activity, "an empty template shows no activity")
+
+	activity, err = defaultPresence.activity(1, &lavalink.Track{Info: lavalink.TrackInfo{Title: strings.Repeat("a", 200)}})
+	require.NoError(t, err)
+	assert.Len(t, []rune(activity.Name), presenceMaxLength)
+}
+
+func Test_ParsePresenceTemplatesInvalid(t *testing.T) {
+	_, err := ParsePresenceTemplates("{{.Title", DefaultServersPresence)
+	assert.Error(t, err)
+}
+
+func Test_Gateway_PresenceFollowsPlayback(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.Presence = defaultPresence
+	f.replay("bot_joined")
+
+	f.playTrack(t)
+	f.replay("bot_left")
+
+	f.discord.AssertGolden("presence")
+}
+
+func Test_Gateway_PresenceOffByDefault(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.replay("bot_joined")
+
+	f.playTrack(t)
+	f.replay("bot_left")
+
+	for _, sent := range f.discord.Gateway.Sent() {
+		assert.NotEqual(t, gateway.OpcodePresenceUpdate, sent.Op)
+	}
+}
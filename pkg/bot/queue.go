@@ -2,6 +2,7 @@ package bot
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 
 	"github.com/disgoorg/snowflake/v2"
@@ -33,21 +34,28 @@ func (q QueueType) String() string {
 }
 
 type Queue struct {
+	mu     sync.Mutex
 	Tracks []lavalink.Track
 	Type   QueueType
 }
 
 func (q *Queue) Shuffle() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	rng.Shuffle(len(q.Tracks), func(i, j int) {
 		q.Tracks[i], q.Tracks[j] = q.Tracks[j], q.Tracks[i]
 	})
 }
 
 func (q *Queue) Add(track ...lavalink.Track) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	q.Tracks = append(q.Tracks, track...)
 }
 
 func (q *Queue) Next() (lavalink.Track, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	if len(q.Tracks) == 0 {
 		return lavalink.Track{}, false
 	}
@@ -56,26 +64,61 @@ func (q *Queue) Next() (lavalink.Track, bool) {
 	return track, true
 }
 
+// Skip drops amount tracks from the front of the queue and returns the track then at its front. Skipping as many
+// tracks as are queued or more empties the queue and returns false.
 func (q *Queue) Skip(amount int) (lavalink.Track, bool) {
-	if len(q.Tracks) == 0 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if amount >= len(q.Tracks) {
+		q.Tracks = q.Tracks[:0]
 		return lavalink.Track{}, false
 	}
-	if amount > len(q.Tracks) {
-		amount = len(q.Tracks)
-	}
-	q.Tracks = q.Tracks[amount:]
+	q.Tracks = q.Tracks[max(amount, 0):]
 	return q.Tracks[0], true
 }
 
 func (q *Queue) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	q.Tracks = make([]lavalink.Track, 0)
 }
 
+// Remove removes the track at index and returns it.
+func (q *Queue) Remove(index int) (lavalink.Track, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if index < 0 || index >= len(q.Tracks) {
+		return lavalink.Track{}, false
+	}
+	track := q.Tracks[index]
+	q.Tracks = append(q.Tracks[:index], q.Tracks[index+1:]...)
+	return track, true
+}
+
+// Snapshot returns a copy of the queued tracks which is safe to use while the queue is modified.
+func (q *Queue) Snapshot() []lavalink.Track {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tracks := make([]lavalink.Track, len(q.Tracks))
+	copy(tracks, q.Tracks)
+	return tracks
+}
+
+// Len returns the number of queued tracks.
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.Tracks)
+}
+
 type QueueManager struct {
+	mu     sync.Mutex
 	queues map[snowflake.ID]*Queue
 }
 
 func (q *QueueManager) Get(guildID snowflake.ID) *Queue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	queue, ok := q.queues[guildID]
 	if !ok {
 		queue = &Queue{
@@ -88,5 +131,16 @@ func (q *QueueManager) Get(guildID snowflake.ID) *Queue {
 }
 
 func (q *QueueManager) Delete(guildID snowflake.ID) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
 	delete(q.queues, guildID)
 }
+
+// ForEach calls fn for every existing queue while holding the manager lock.
+func (q *QueueManager) ForEach(fn func(guildID snowflake.ID, queue *Queue)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for guildID, queue := range q.queues {
+		fn(guildID, queue)
+	}
+}
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-discord-music/pkg/queuefile"
+
+	"github.com/Cyb3r-Jak3/common/v5"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+const (
+	// maxQueueFileSize is the largest queue file accepted by /queue-import.
+	maxQueueFileSize = 1 << 20
+	// maxQueueFileTracks is the number of entries read from an imported queue file.
+	maxQueueFileTracks = 500
+	// maxImportFailuresShown is the number of failed entries listed in the /queue-import response.
+	maxImportFailuresShown = 10
+	// attachmentTimeout bounds downloading a queue file.
+	attachmentTimeout = 10 * time.Second
+)
+
+func (b *Bot) queueExport(_ context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	format := queuefile.FormatJSON
+	if value, ok := data.OptString("format"); ok {
+		format = queuefile.Format(value)
+	}
+	tracks := b.Music.Tracks(*event.GuildID())
+	if len(tracks) == 0 {
+		return event.CreateMessage(discord.MessageCreate{
+			Content: "No tracks in queue",
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := queuefile.Encode(&buf, format, tracks); err != nil {
+		return serviceError("exporting the queue", err)
+	}
+	return event.CreateMessage(discord.MessageCreate{
+		Content: fmt.Sprintf("Exported `%d` tracks, import them with `/queue-import`", len(tracks)),
+		Files:   []*discord.File{discord.NewFile(format.FileName(), "", &buf)},
+	})
+}
+
+func (b *Bot) queueImport(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	attachment := data.Attachment("file")
+	format, err := queuefile.FormatFromName(attachment.Filename)
+	if err != nil {
+		return userErrorf("Queue files need to be `.json` or `.m3u` files")
+	}
+	if attachment.Size > maxQueueFileSize {
+		return userErrorf("Queue files can be at most `%d` KiB", maxQueueFileSize>>10)
+	}
+	channelID, err := b.Music.JoinableChannel(*event.GuildID(), event.User().ID)
+	if err != nil {
+		return serviceError("joining your voice channel", err)
+	}
+	if err = event.DeferCreateMessage(false); err != nil {
+		return err
+	}
+
+	content, err := b.importQueue(ctx, *event.GuildID(), channelID, event.User().ID, attachment.URL, format)
+	if err != nil {
+		return serviceError("importing the queue", err)
+	}
+	_, updateErr := b.Client.Rest().UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: common.Ptr(content),
+	})
+	return updateErr
+}
+
+// importQueue downloads and resolves a queue file and plays its tracks. It returns the response describing the result.
+func (b *Bot) importQueue(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID, userID snowflake.ID, url string, format queuefile.Format) (string, error) {
+	body, err := b.downloadAttachment(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	entries, err := queuefile.Decode(bytes.NewReader(body), format, maxQueueFileTracks)
+	if err != nil {
+		return "", &UserError{Message: fmt.Sprintf("Could not read the queue file: `%s`", err), Err: err}
+	}
+	if len(entries) == 0 {
+		return "The queue file does not contain any tracks", nil
+	}
+
+	tracks, failures := b.Music.Import(ctx, entries)
+	content := fmt.Sprintf("Imported `%d` of `%d` tracks", len(tracks), len(entries))
+	if len(tracks) > 0 {
+		result, playErr := b.Music.PlayTracks(ctx, guildID, channelID, userID, tracks)
+		if playErr != nil {
+			return "", playErr
+		}
+		if !result.Queued {
+			content += fmt.Sprintf(", now playing [`%s`](<%s>)", result.Track.Info.Title, queuefile.Identifier(result.Track))
+		}
+		content += rejectionNote(result.Rejected)
+	}
+	if len(failures) > 0 {
+		content += "\nCould not load:"
+		for i, failure := range failures {
+			if i == maxImportFailuresShown {
+				content += fmt.Sprintf("\n... and `%d` more", len(failures)-maxImportFailuresShown)
+				break
+			}
+			content += fmt.Sprintf("\n- `%s`: %s", failure.Entry.Name(), failure.Err)
+		}
+	}
+	return content, nil
+}
+
+// downloadAttachment fetches a queue file attached to a command from the Discord CDN. Files larger than
+// maxQueueFileSize are rejected. It doesn't use HTTPClient, which keeps the sticky session of the Lavalink node.
+func (b *Bot) downloadAttachment(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.cdnClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading attachment: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error downloading attachment: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxQueueFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("error downloading attachment: %w", err)
+	}
+	if len(body) > maxQueueFileSize {
+		return nil, userErrorf("Queue files can be at most `%d` KiB", maxQueueFileSize>>10)
+	}
+	return body, nil
+}
@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-discord-music/pkg/queuefile"
+
+	"github.com/Cyb3r-Jak3/common/v5"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// attachmentTransport serves fixed attachment contents by URL.
+type attachmentTransport map[string]string
+
+func (a attachmentTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	content, ok := a[r.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: http.NoBody, Request: r}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(strings.NewReader(content)), Request: r}, nil
+}
+
+func Test_MusicService_Import(t *testing.T) {
+	f := newServiceFixture()
+	f.loader.results["https://example.com/a"] = &lavalink.LoadResult{Data: testTrack("a")}
+	f.loader.results["https://example.com/b"] = &lavalink.LoadResult{Data: testTrack("b")}
+	stale := testTrack("stale")
+
+	tracks, failures := f.service.Import(context.Background(), []queuefile.Entry{
+		{Identifier: "https://example.com/a", Track: common.Ptr(testTrack("a"))},
+		{Identifier: "https://example.com/b", Track: &stale},
+		{Identifier: "https://example.com/missing", Title: "Missing"},
+	})
+
+	assert.Equal(t, []lavalink.Track{testTrack("a"), testTrack("b")}, tracks)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "Missing", failures[0].Entry.Name())
+	assert.ErrorIs(t, failures[0].Err, ErrNoMatches)
+}
+
+func Test_MusicService_ImportResolvesLikePlay(t *testing.T) {
+	f := newServiceFixture()
+	f.loader.results["ytsearch:Artist - Title"] = &lavalink.LoadResult{Data: lavalink.Search{testTrack("a"), testTrack("b")}}
+
+	tracks, failures := f.service.Import(context.Background(), []queuefile.Entry{
+		{Identifier: "Artist - Title"},
+		{Identifier: "/music/a.mp3"},
+		{Identifier: "file:///etc/passwd"},
+		{Identifier: `C:\Music\a.mp3`},
+		{Identifier: "../a.mp3"},
+		{Identifier: "ftp://example.com/a.mp3"},
+	})
+
+	assert.Equal(t, []lavalink.Track{testTrack("a")}, tracks, "only the first search result is imported")
+	require.Len(t, failures, 5)
+	for _, failure := range failures {
+		assert.ErrorIs(t, failure.Err, ErrLocalIdentifier, failure.Entry.Identifier)
+	}
+}
+
+func Test_DownloadAttachmentLimitsSize(t *testing.T) {
+	b := newBot(logrus.New())
+	b.cdnClient = &http.Client{Transport: attachmentTransport{
+		"https://cdn.example.com/small.m3u": "https://example.com/a\n",
+		"https://cdn.example.com/large.m3u": strings.Repeat("a", maxQueueFileSize+1),
+	}}
+
+	body, err := b.downloadAttachment(context.Background(), "https://cdn.example.com/small.m3u")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/a\n", string(body))
+
+	_, err = b.downloadAttachment(context.Background(), "https://cdn.example.com/large.m3u")
+	var userErr *UserError
+	assert.ErrorAs(t, err, &userErr)
+}
+
+func Test_Gateway_QueueExport(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.Queues.Get(testGuildID).Add(
+		lavalink.Track{Encoded: "a", Info: lavalink.TrackInfo{Title: "Track A", Author: "Artist", Length: 3 * lavalink.Minute, URI: common.Ptr("https://example.com/a")}},
+		lavalink.Track{Encoded: "b", Info: lavalink.TrackInfo{Title: "Radio", Author: "Station", IsStream: true}},
+	)
+
+	f.replay("queue_export")
+
+	f.discord.AssertGolden("queue_export")
+}
+
+func Test_Gateway_QueueImport(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.server.AddTrack("https://example.com/a", lavalink.Track{Encoded: "a", Info: lavalink.TrackInfo{Title: "Track A", URI: common.Ptr("https://example.com/a")}})
+	f.server.AddTrack("https://example.com/b", lavalink.Track{Encoded: "b", Info: lavalink.TrackInfo{Title: "Track B", URI: common.Ptr("https://example.com/b")}})
+	f.bot.cdnClient = &http.Client{Transport: attachmentTransport{
+		"https://cdn.example.com/queue.m3u": "#EXTM3U\n#EXTINF:180,Artist - Track A\nhttps://example.com/a\nhttps://example.com/missing\nhttps://example.com/b\n",
+	}}
+
+	f.replay("queue_import")
+
+	f.discord.AssertGolden("queue_import")
+	assert.Equal(t, "a", f.server.CurrentTrack(testGuildID))
+	queued := f.bot.Queues.Get(testGuildID).Snapshot()
+	require.Len(t, queued, 1)
+	assert.Equal(t, "b", queued[0].Encoded)
+}
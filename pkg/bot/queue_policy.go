@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go-discord-music/pkg/format"
+	"go-discord-music/pkg/policy"
+	"go-discord-music/pkg/store"
+	"go-discord-music/pkg/timestamp"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+const (
+	// maxRejectionsShown is the number of rejected tracks listed in a response.
+	maxRejectionsShown = 5
+	// maxBlocklistEntries keeps the blocklist of a guild within a single message.
+	maxBlocklistEntries = 50
+)
+
+// queuePolicy returns the queue policy of the guild.
+func (b *Bot) queuePolicy(guildID snowflake.ID) policy.Policy {
+	if p := b.Store.Guild(guildID).QueuePolicy; p != nil {
+		return *p
+	}
+	return policy.Policy{}
+}
+
+// updateQueuePolicy applies fn to a copy of the guild's queue policy and stores the result.
+func (b *Bot) updateQueuePolicy(guildID snowflake.ID, fn func(p *policy.Policy)) (policy.Policy, error) {
+	var updated policy.Policy
+	err := b.Store.UpdateGuild(guildID, func(settings *store.GuildSettings) error {
+		if settings.QueuePolicy != nil {
+			updated = *settings.QueuePolicy
+		}
+		updated.AllowedSources = slices.Clone(updated.AllowedSources)
+		updated.Blocklist = slices.Clone(updated.Blocklist)
+		fn(&updated)
+		if updated.Empty() {
+			settings.QueuePolicy = nil
+		} else {
+			settings.QueuePolicy = &updated
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// rejectionList renders rejected tracks as a list, one per line.
+func rejectionList(rejected []policy.Rejection) string {
+	var list strings.Builder
+	for i, rejection := range rejected {
+		if i == maxRejectionsShown {
+			fmt.Fprintf(&list, "\n... and `%d` more", len(rejected)-maxRejectionsShown)
+			break
+		}
+		fmt.Fprintf(&list, "\n- %s", rejection)
+	}
+	return list.String()
+}
+
+// rejectionNote describes the tracks the queue policy did not admit, to be appended to a response.
+func rejectionNote(rejected []policy.Rejection) string {
+	if len(rejected) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nSkipped `%d` tracks because of the server's queue policy:%s", len(rejected), rejectionList(rejected))
+}
+
+func (b *Bot) queuePolicyCommand(_ context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	guildID := *event.GuildID()
+	if data.SubCommandName == nil {
+		return userErrorf("Unknown queue policy command")
+	}
+
+	var (
+		updated policy.Policy
+		err     error
+		content string
+	)
+	switch *data.SubCommandName {
+	case "show":
+		return event.CreateMessage(discord.MessageCreate{
+			Content: queuePolicyString(b.queuePolicy(guildID)),
+		})
+	case "set":
+		var maxTrackLength *time.Duration
+		if value, ok := data.OptString("max-track-length"); ok {
+			position, parseErr := timestamp.Parse(value, time.Minute)
+			if parseErr != nil || position.Relative {
+				return userErrorf("Invalid length `%s`, use a duration like `10m` or `1:30:00`, or `0` for no limit", value)
+			}
+			maxTrackLength = &position.Offset
+		}
+		updated, err = b.updateQueuePolicy(guildID, func(p *policy.Policy) {
+			if value, ok := data.OptInt("max-queue-length"); ok {
+				p.MaxQueueLength = value
+			}
+			if value, ok := data.OptInt("max-tracks-per-user"); ok {
+				p.MaxTracksPerUser = value
+			}
+			if maxTrackLength != nil {
+				p.MaxTrackLength = lavalinkDuration(*maxTrackLength)
+			}
+			if value, ok := data.OptBool("streams"); ok {
+				p.DenyStreams = !value
+			}
+			if value, ok := data.OptString("sources"); ok {
+				p.AllowedSources = parseSources(value)
+			}
+		})
+		content = "Queue policy updated\n"
+	case "block":
+		entry := strings.TrimSpace(data.String("entry"))
+		if entry == "" {
+			return userErrorf("Blocklist entries can't be empty")
+		}
+		current := b.queuePolicy(guildID)
+		if slices.ContainsFunc(current.Blocklist, func(e string) bool { return strings.EqualFold(e, entry) }) {
+			return userErrorf("`%s` is already blocked", entry)
+		}
+		if len(current.Blocklist) >= maxBlocklistEntries {
+			return userErrorf("The blocklist can hold at most `%d` entries", maxBlocklistEntries)
+		}
+		updated, err = b.updateQueuePolicy(guildID, func(p *policy.Policy) {
+			p.Blocklist = append(p.Blocklist, entry)
+		})
+		content = fmt.Sprintf("Blocked `%s`\n", entry)
+	case "unblock":
+		entry := strings.TrimSpace(data.String("entry"))
+		removed := false
+		updated, err = b.updateQueuePolicy(guildID, func(p *policy.Policy) {
+			p.Blocklist = slices.DeleteFunc(p.Blocklist, func(e string) bool {
+				if strings.EqualFold(e, entry) {
+					removed = true
+					return true
+				}
+				return false
+			})
+		})
+		if err == nil && !removed {
+			return userErrorf("`%s` is not blocked", entry)
+		}
+		content = fmt.Sprintf("Unblocked `%s`\n", entry)
+	case "reset":
+		updated, err = b.updateQueuePolicy(guildID, func(p *policy.Policy) {
+			*p = policy.Policy{}
+		})
+		content = "Queue policy reset\n"
+	default:
+		return userErrorf("Unknown queue policy command: `%s`", *data.SubCommandName)
+	}
+	if err != nil {
+		return serviceError("updating the queue policy", err)
+	}
+	return event.CreateMessage(discord.MessageCreate{
+		Content: content + queuePolicyString(updated),
+	})
+}
+
+// parseSources parses a comma separated list of source managers. "all" or an empty list allows every source.
+func parseSources(value string) []string {
+	var sources []string
+	for _, source := range strings.Split(value, ",") {
+		source = strings.ToLower(strings.TrimSpace(source))
+		if source == "all" {
+			return nil
+		}
+		if source != "" && !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// queuePolicyString describes a queue policy for chat messages.
+func queuePolicyString(p policy.Policy) string {
+	if p.Empty() {
+		return "No queue limits are set"
+	}
+	limit := func(value int) string {
+		if value == 0 {
+			return "no limit"
+		}
+		return fmt.Sprintf("`%d`", value)
+	}
+	maxTrackLength := "no limit"
+	if p.MaxTrackLength > 0 {
+		maxTrackLength = fmt.Sprintf("`%s`", format.Duration(p.MaxTrackLength))
+	}
+	sources := "all"
+	if len(p.AllowedSources) > 0 {
+		sources = "`" + strings.Join(p.AllowedSources, "`, `") + "`"
+	}
+	streams := "allowed"
+	if p.DenyStreams {
+		streams = "denied"
+	}
+	blocklist := "empty"
+	if len(p.Blocklist) > 0 {
+		blocklist = "`" + strings.Join(p.Blocklist, "`, `") + "`"
+	}
+	return fmt.Sprintf("Max queue length: %s\nMax tracks per user: %s\nMax track length: %s\nLive streams: %s\nSources: %s\nBlocklist: %s",
+		limit(p.MaxQueueLength), limit(p.MaxTracksPerUser), maxTrackLength, streams, sources, blocklist)
+}
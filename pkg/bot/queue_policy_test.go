@@ -0,0 +1,41 @@
+package bot
+
+import (
+	"testing"
+
+	"go-discord-music/pkg/policy"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Gateway_QueuePolicyRejectsTrack(t *testing.T) {
+	f := newGatewayFixture(t)
+	uri := "https://example.com/a"
+	f.server.AddTrack(uri, lavalink.Track{Encoded: "a", Info: lavalink.TrackInfo{Title: "Track A", URI: &uri, Length: lavalink.Minute}})
+
+	f.replay("queue_policy_set")
+	f.replay("play")
+
+	f.discord.AssertGolden("queue_policy_rejected")
+	assert.Equal(t, policy.Policy{MaxTrackLength: 30 * lavalink.Second, DenyStreams: true}, f.bot.queuePolicy(testGuildID))
+	assert.Empty(t, f.server.CurrentTrack(testGuildID))
+}
+
+func Test_UpdateQueuePolicyRemovesEmptyPolicy(t *testing.T) {
+	b := newBot(nil)
+
+	updated, err := b.updateQueuePolicy(testGuildID, func(p *policy.Policy) { p.Blocklist = append(p.Blocklist, "a") })
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, updated.Blocklist)
+
+	_, err = b.updateQueuePolicy(testGuildID, func(p *policy.Policy) { p.Blocklist = nil })
+	require.NoError(t, err)
+	assert.Nil(t, b.Store.Guild(testGuildID).QueuePolicy)
+}
+
+func Test_ParseSources(t *testing.T) {
+	assert.Equal(t, []string{"youtube", "deezer"}, parseSources(" YouTube, deezer,,youtube "))
+	assert.Nil(t, parseSources("youtube,all"))
+}
@@ -67,6 +67,29 @@ func Test_Queue_Skip_ReturnsFalseWhenEmpty(t *testing.T) {
 	assert.Equal(t, lavalink.Track{}, track)
 }
 
+func Test_Queue_Skip_WholeQueue(t *testing.T) {
+	queue := &Queue{}
+	queue.Add(lavalink.Track{Encoded: "track1"})
+
+	track, ok := queue.Skip(1)
+
+	assert.False(t, ok)
+	assert.Equal(t, lavalink.Track{}, track)
+	assert.Empty(t, queue.Tracks)
+}
+
+func Test_Queue_Skip_NegativeAmount(t *testing.T) {
+	queue := &Queue{}
+	track1 := lavalink.Track{Encoded: "track1"}
+	queue.Add(track1)
+
+	track, ok := queue.Skip(-1)
+
+	assert.True(t, ok)
+	assert.Equal(t, track1, track)
+	assert.Len(t, queue.Tracks, 1)
+}
+
 func Test_Queue_Clear_RemovesAllTracks(t *testing.T) {
 	queue := &Queue{}
 	track1 := lavalink.Track{Encoded: "track1"}
@@ -110,3 +133,52 @@ func Test_QueueManager_Delete_RemovesQueue(t *testing.T) {
 	_, exists := manager.queues[guildID]
 	assert.False(t, exists)
 }
+
+func Test_QueueManager_ForEach_VisitsAllQueues(t *testing.T) {
+	manager := &QueueManager{queues: make(map[snowflake.ID]*Queue)}
+	manager.Get(snowflake.ID(1))
+	manager.Get(snowflake.ID(2))
+
+	visited := make(map[snowflake.ID]bool)
+	manager.ForEach(func(guildID snowflake.ID, _ *Queue) {
+		visited[guildID] = true
+	})
+
+	assert.Equal(t, map[snowflake.ID]bool{1: true, 2: true}, visited)
+}
+
+func Test_Queue_Remove_RemovesTrackAtIndex(t *testing.T) {
+	queue := &Queue{}
+	track1 := lavalink.Track{Encoded: "track1"}
+	track2 := lavalink.Track{Encoded: "track2"}
+	track3 := lavalink.Track{Encoded: "track3"}
+	queue.Add(track1, track2, track3)
+
+	track, ok := queue.Remove(1)
+
+	assert.True(t, ok)
+	assert.Equal(t, track2, track)
+	assert.Equal(t, []lavalink.Track{track1, track3}, queue.Tracks)
+}
+
+func Test_Queue_Remove_ReturnsFalseWhenOutOfRange(t *testing.T) {
+	queue := &Queue{}
+	queue.Add(lavalink.Track{Encoded: "track1"})
+
+	_, ok := queue.Remove(1)
+	assert.False(t, ok)
+
+	_, ok = queue.Remove(-1)
+	assert.False(t, ok)
+	assert.Equal(t, 1, queue.Len())
+}
+
+func Test_Queue_Snapshot_ReturnsCopy(t *testing.T) {
+	queue := &Queue{}
+	queue.Add(lavalink.Track{Encoded: "track1"})
+
+	snapshot := queue.Snapshot()
+	snapshot[0].Encoded = "changed"
+
+	assert.Equal(t, "track1", queue.Tracks[0].Encoded)
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go-discord-music/pkg/format"
+	"go-discord-music/pkg/metrics"
+	"go-discord-music/pkg/ratelimit"
+	"go-discord-music/pkg/store"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// defaultRateLimits are the per user command rate limits used unless overridden with WithRateLimits. Commands which
+// start playback or change the queue are limited harder than the rest.
+var defaultRateLimits = ratelimit.Limits{
+	ratelimit.Default: {Burst: 5, Per: 10 * time.Second},
+	"play":            {Burst: 3, Per: 10 * time.Second},
+	"skip":            {Burst: 3, Per: 10 * time.Second},
+	"queue-import":    {Burst: 1, Per: 30 * time.Second},
+	"lyrics":          {Burst: 2, Per: 10 * time.Second},
+}
+
+// rateLimits returns the command rate limits of the guild, its overrides taking precedence over the bot's limits.
+func (b *Bot) rateLimits(guildID snowflake.ID) ratelimit.Limits {
+	limits := maps.Clone(b.RateLimits)
+	if limits == nil {
+		limits = make(ratelimit.Limits)
+	}
+	maps.Copy(limits, b.Store.Guild(guildID).RateLimits)
+	return limits
+}
+
+// commandRateLimit returns the rate limit of command in the guild. A limit for the command itself, set by the guild or
+// the bot, takes precedence over the guild's and then the bot's default limit.
+func (b *Bot) commandRateLimit(guildID snowflake.ID, command string) ratelimit.Limit {
+	limit, _ := b.rateLimits(guildID).Lookup(command)
+	return limit
+}
+
+// throttle reports whether the user has used command too often, in which case they are asked to slow down.
+func (b *Bot) throttle(event *events.ApplicationCommandInteractionCreate, command string) bool {
+	var guildID snowflake.ID
+	if event.GuildID() != nil {
+		guildID = *event.GuildID()
+	}
+	userID := event.User().ID
+	allowed, retryAfter := b.rateLimiter.Allow(fmt.Sprintf("%d/%d/%s", guildID, userID, command), b.commandRateLimit(guildID, command))
+	if allowed {
+		return false
+	}
+
+	metrics.CommandsThrottled.WithLabelValues(command).Inc()
+	metrics.CommandsHandled.WithLabelValues(command, metrics.OutcomeThrottled).Inc()
+	b.logger.Debugf("throttled command %s of user %s in guild %s for %s", command, userID, guildID, retryAfter)
+	err := event.CreateMessage(discord.MessageCreate{
+		Content: fmt.Sprintf("Slow down! You can use `/%s` again in `%s`", command, format.Duration(lavalink.Duration((retryAfter + time.Second - 1).Truncate(time.Second).Milliseconds()))),
+		Flags:   discord.MessageFlagEphemeral,
+	})
+	if err != nil {
+		b.logger.Errorf("error sending rate limit response for command %s: %v", command, err)
+	}
+	return true
+}
+
+// updateRateLimits applies fn to a copy of the guild's rate limit overrides and stores the result.
+func (b *Bot) updateRateLimits(guildID snowflake.ID, fn func(limits ratelimit.Limits)) error {
+	return b.Store.UpdateGuild(guildID, func(settings *store.GuildSettings) error {
+		updated := maps.Clone(settings.RateLimits)
+		if updated == nil {
+			updated = make(ratelimit.Limits)
+		}
+		fn(updated)
+		if len(updated) == 0 {
+			updated = nil
+		}
+		settings.RateLimits = updated
+		return nil
+	})
+}
+
+func (b *Bot) rateLimitCommand(_ context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	guildID := *event.GuildID()
+	if data.SubCommandName == nil {
+		return userErrorf("Unknown rate limit command")
+	}
+
+	command, hasCommand := data.OptString("command")
+	command = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(command)), "/")
+	if _, ok := b.Handlers[command]; hasCommand && !ok && command != ratelimit.Default {
+		return userErrorf("Unknown command `%s`", command)
+	}
+
+	var (
+		err     error
+		content string
+	)
+	switch *data.SubCommandName {
+	case "show":
+		return event.CreateMessage(discord.MessageCreate{
+			Content: b.rateLimitsString(guildID),
+		})
+	case "set":
+		limit, parseErr := ratelimit.Parse(data.String("limit"))
+		if parseErr != nil {
+			return userErrorf("Invalid limit `%s`, use uses per period like `3/10s`, or `off` for no limit", data.String("limit"))
+		}
+		err = b.updateRateLimits(guildID, func(limits ratelimit.Limits) {
+			limits[command] = limit
+		})
+		content = fmt.Sprintf("Rate limit of %s set to `%s`\n", rateLimitName(command), limit)
+	case "reset":
+		err = b.updateRateLimits(guildID, func(limits ratelimit.Limits) {
+			if hasCommand {
+				delete(limits, command)
+			} else {
+				clear(limits)
+			}
+		})
+		content = "Rate limits reset\n"
+		if hasCommand {
+			content = fmt.Sprintf("Rate limit of %s reset\n", rateLimitName(command))
+		}
+	default:
+		return userErrorf("Unknown rate limit command: `%s`", *data.SubCommandName)
+	}
+	if err != nil {
+		return serviceError("updating the rate limits", err)
+	}
+	return event.CreateMessage(discord.MessageCreate{
+		Content: content + b.rateLimitsString(guildID),
+	})
+}
+
+// rateLimitName names a command of a rate limit in chat messages.
+func rateLimitName(command string) string {
+	if command == ratelimit.Default {
+		return "other commands"
+	}
+	return fmt.Sprintf("`/%s`", command)
+}
+
+// rateLimitsString lists the command rate limits of a guild, marking those set by the guild.
+func (b *Bot) rateLimitsString(guildID snowflake.ID) string {
+	overrides := b.Store.Guild(guildID).RateLimits
+	limits := b.rateLimits(guildID)
+	commands := slices.Sorted(maps.Keys(limits))
+	// The default is listed last as it applies to everything else.
+	commands = slices.DeleteFunc(commands, func(command string) bool { return command == ratelimit.Default })
+	commands = append(commands, ratelimit.Default)
+
+	var list strings.Builder
+	list.WriteString("Command rate limits per member:")
+	for _, command := range commands {
+		fmt.Fprintf(&list, "\n- %s: `%s`", rateLimitName(command), limits[command])
+		if _, ok := overrides[command]; ok {
+			list.WriteString(" (server)")
+		}
+	}
+	return list.String()
+}
@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Gateway_RateLimitThrottlesCommand(t *testing.T) {
+	f := newGatewayFixture(t)
+	now := time.Unix(0, 0)
+	f.bot.rateLimiter = ratelimit.New(func() time.Time { return now })
+
+	f.replay("rate_limit_set")
+
+	f.discord.AssertGolden("rate_limit_throttled")
+	assert.Equal(t, ratelimit.Limits{"pause": {Burst: 1, Per: time.Minute}}, f.bot.Store.Guild(testGuildID).RateLimits)
+}
+
+func Test_CommandRateLimitPrecedence(t *testing.T) {
+	b := newBot(nil)
+	b.RateLimits = ratelimit.Limits{
+		ratelimit.Default: {Burst: 5, Per: 10 * time.Second},
+		"play":            {Burst: 3, Per: 10 * time.Second},
+	}
+	require.NoError(t, b.updateRateLimits(testGuildID, func(limits ratelimit.Limits) {
+		limits[ratelimit.Default] = ratelimit.Limit{}
+		limits["skip"] = ratelimit.Limit{Burst: 1, Per: time.Second}
+	}))
+
+	assert.Equal(t, ratelimit.Limit{Burst: 3, Per: 10 * time.Second}, b.commandRateLimit(testGuildID, "play"))
+	assert.Equal(t, ratelimit.Limit{Burst: 1, Per: time.Second}, b.commandRateLimit(testGuildID, "skip"))
+	assert.True(t, b.commandRateLimit(testGuildID, "pause").Unlimited())
+	assert.Equal(t, ratelimit.Limit{Burst: 5, Per: 10 * time.Second}, b.commandRateLimit(testGuildID+1, "pause"))
+
+	require.NoError(t, b.updateRateLimits(testGuildID, func(limits ratelimit.Limits) { clear(limits) }))
+	assert.Nil(t, b.Store.Guild(testGuildID).RateLimits)
+}
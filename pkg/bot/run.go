@@ -18,35 +18,81 @@ func (b *Bot) Run() {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		b.logger.Debugf("No Lavalink nodes found, adding default node: %s", defaultLavalinkNode.Name)
-		_, addNodeErr := b.Lavalink.AddNode(ctx, defaultLavalinkNode)
+		_, addNodeErr := b.addNode(ctx, defaultLavalinkNode)
 		if addNodeErr != nil {
 			b.logger.Fatalf("error adding default lavalink node: %v", addNodeErr)
 		}
 	}
-	b.Handlers = map[string]func(event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error{
-		"play":        b.play,
-		"pause":       b.pause,
-		"now-playing": b.nowPlaying,
-		"stop":        b.stop,
-		"players":     b.players,
-		"queue":       b.queue,
-		"clear-queue": b.clearQueue,
-		"queue-type":  b.queueType,
-		"shuffle":     b.shuffle,
-		"seek":        b.seek,
-		"volume":      b.volume,
-		"skip":        b.skip,
-		"bass-boost":  b.bassBoost,
-		"disconnect":  b.disconnect,
-		"connect":     b.connect,
-		"debug":       b.debug,
-		"source":      b.source,
-	}
+	b.Handlers = b.commandHandlers()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	if err := b.Client.OpenGateway(ctx); err != nil {
+	if err := b.openGateway(ctx); err != nil {
 		b.logger.Fatalf("error opening discord gateway: %v", err)
 	}
-	go b.IdleTimeoutCleaner()
+	b.startHTTPServer()
+	b.startAPIServer()
+}
+
+// defaultCommandTimeout bounds how long a command handler may take.
+const defaultCommandTimeout = 10 * time.Second
+
+// commandTimeouts overrides defaultCommandTimeout for slow commands, keyed by command name.
+var commandTimeouts = map[string]time.Duration{
+	"queue-import": 30 * time.Second,
+}
+
+// commandTimeout returns the timeout of the context passed to the handler of command.
+func commandTimeout(command string) time.Duration {
+	if timeout, ok := commandTimeouts[command]; ok {
+		return timeout
+	}
+	return defaultCommandTimeout
+}
+
+// eventTimeout bounds the work done in response to a Discord or Lavalink event, such as a voice update or a track
+// ending.
+const eventTimeout = 10 * time.Second
+
+// inBotChannel runs handler only when the user is in the bot's voice channel, see MusicService.RequireSameChannel.
+func (b *Bot) inBotChannel(handler CommandHandler) CommandHandler {
+	return func(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+		if err := b.Music.RequireSameChannel(*event.GuildID(), event.User().ID); err != nil {
+			return serviceError("checking your voice channel", err)
+		}
+		return handler(ctx, event, data)
+	}
+}
+
+// commandHandlers returns the handlers for each slash command, keyed by command name.
+func (b *Bot) commandHandlers() map[string]CommandHandler {
+	return map[string]CommandHandler{
+		"play":         b.play,
+		"pause":        b.inBotChannel(b.pause),
+		"now-playing":  b.nowPlaying,
+		"stop":         b.inBotChannel(b.stop),
+		"players":      b.players,
+		"queue":        b.queue,
+		"clear-queue":  b.inBotChannel(b.clearQueue),
+		"queue-type":   b.inBotChannel(b.queueType),
+		"shuffle":      b.inBotChannel(b.shuffle),
+		"seek":         b.inBotChannel(b.seek),
+		"forward":      b.inBotChannel(b.forward),
+		"rewind":       b.inBotChannel(b.rewind),
+		"volume":       b.inBotChannel(b.volume),
+		"skip":         b.inBotChannel(b.skip),
+		"bass-boost":   b.inBotChannel(b.bassBoost),
+		"disconnect":   b.inBotChannel(b.disconnect),
+		"connect":      b.connect,
+		"move-here":    b.moveHere,
+		"debug":        b.debug,
+		"source":       b.source,
+		"24-7":         b.alwaysOnCommand,
+		"playlist":     b.playlist,
+		"queue-export": b.queueExport,
+		"queue-import": b.queueImport,
+		"queue-policy": b.queuePolicyCommand,
+		"rate-limit":   b.rateLimitCommand,
+		"lyrics":       b.lyricsCommand,
+	}
 }
@@ -0,0 +1,80 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-discord-music/pkg/format"
+	"go-discord-music/pkg/timestamp"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// defaultSeekAmount is how far /forward and /rewind move without an amount.
+const defaultSeekAmount = 10 * time.Second
+
+func (b *Bot) seek(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	unit, ok := data.OptInt("unit")
+	if !ok {
+		unit = int(lavalink.Second)
+	}
+	position, err := timestamp.Parse(data.String("position"), time.Duration(unit)*time.Millisecond)
+	if err != nil {
+		return userErrorf("Invalid position `%s`, use a timestamp like `1:23` or `90s`, or an offset like `+30s`", data.String("position"))
+	}
+
+	finalPosition, err := b.Music.SeekTo(ctx, *event.GuildID(), position)
+	if err != nil {
+		return serviceError("seeking", err)
+	}
+
+	return event.CreateMessage(discord.MessageCreate{
+		Content: b.seekedContent(*event.GuildID(), "Seeked", finalPosition),
+	})
+}
+
+func (b *Bot) forward(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	return b.seekBy(ctx, event, data, 1, "Skipped forward")
+}
+
+func (b *Bot) rewind(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData) error {
+	return b.seekBy(ctx, event, data, -1, "Rewound")
+}
+
+// seekBy moves the current track by the amount option in the given direction.
+func (b *Bot) seekBy(ctx context.Context, event *events.ApplicationCommandInteractionCreate, data discord.SlashCommandInteractionData, direction time.Duration, action string) error {
+	amount := defaultSeekAmount
+	if value, ok := data.OptString("amount"); ok {
+		position, err := timestamp.Parse(value, time.Second)
+		if err != nil || position.Relative {
+			return userErrorf("Invalid amount `%s`, use a duration like `30s` or `1:00`", value)
+		}
+		amount = position.Offset
+	}
+
+	position, err := b.Music.SeekTo(ctx, *event.GuildID(), timestamp.Position{Offset: direction * amount, Relative: true})
+	if err != nil {
+		return serviceError("seeking", err)
+	}
+	return event.CreateMessage(discord.MessageCreate{
+		Content: b.seekedContent(*event.GuildID(), action, position),
+	})
+}
+
+// seekedContent confirms a seek to position with the progress through the current track.
+func (b *Bot) seekedContent(guildID snowflake.ID, action string, position lavalink.Duration) string {
+	nowPlaying, err := b.Music.NowPlaying(guildID)
+	if err != nil {
+		return fmt.Sprintf("%s to `%s`", action, format.Duration(position))
+	}
+	info := nowPlaying.Track.Info
+	return fmt.Sprintf("%s to `%s` %s `%s`", action, format.Duration(position), format.Bar(position, info.Length, format.BarWidth), format.Length(info))
+}
+
+func lavalinkDuration(duration time.Duration) lavalink.Duration {
+	return lavalink.Duration(duration.Milliseconds())
+}
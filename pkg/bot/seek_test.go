@@ -0,0 +1,28 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Gateway_Forward(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+
+	f.replay("forward")
+
+	f.discord.AssertGolden("forward")
+	player, ok := f.server.Player(testGuildID)
+	assert.True(t, ok)
+	assert.GreaterOrEqual(t, int64(player.State.Position), int64(30000))
+}
+
+func Test_Gateway_SeekOutOfRange(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+
+	f.replay("seek_out_of_range")
+
+	f.discord.AssertGolden("seek_out_of_range")
+}
@@ -0,0 +1,925 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-discord-music/pkg/policy"
+	"go-discord-music/pkg/queuefile"
+	"go-discord-music/pkg/timestamp"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+var (
+	ErrNoPlayer          = errors.New("no player found")
+	ErrNoTrack           = errors.New("no track playing")
+	ErrNoTracks          = errors.New("no tracks in queue")
+	ErrNoMatches         = errors.New("nothing found")
+	ErrNoNode            = errors.New("no lavalink node available")
+	ErrNotInVoice        = errors.New("you need to be in a voice channel")
+	ErrAlreadyConnected  = errors.New("player already connected")
+	ErrInvalidQueueType  = errors.New("unknown queue type")
+	ErrQueueIndexInvalid = errors.New("queue index out of range")
+	ErrUnsupportedSource = errors.New("search source not supported by the lavalink node")
+	ErrUnsupportedFilter = errors.New("filter not supported by the lavalink node")
+	ErrNotSeekable       = errors.New("streams can't be seeked")
+	ErrSeekOutOfRange    = errors.New("position is outside the track")
+	ErrNotInBotChannel   = errors.New("you need to be in the bot's voice channel")
+	ErrChannelBusy       = errors.New("the bot is playing to listeners in another voice channel")
+	ErrAlreadyInChannel  = errors.New("the bot is already in your voice channel")
+	ErrLocalIdentifier   = errors.New("only links and searches can be loaded")
+)
+
+// Player is the part of disgolink.Player used by MusicService.
+type Player interface {
+	GuildID() snowflake.ID
+	ChannelID() *snowflake.ID
+	Track() *lavalink.Track
+	Paused() bool
+	Position() lavalink.Duration
+	Volume() int
+	Filters() lavalink.Filters
+	Update(ctx context.Context, opts ...lavalink.PlayerUpdateOpt) error
+}
+
+// PlayerProvider gives access to the players of each guild.
+type PlayerProvider interface {
+	// ExistingPlayer returns the guild's player or nil if there is none.
+	ExistingPlayer(guildID snowflake.ID) Player
+	// Player returns the guild's player, creating it when needed.
+	Player(guildID snowflake.ID) Player
+	ForPlayers(fn func(player Player))
+}
+
+// TrackLoader resolves identifiers into tracks.
+type TrackLoader interface {
+	LoadTracks(ctx context.Context, identifier string) (*lavalink.LoadResult, error)
+	DecodeTrack(ctx context.Context, encoded string) (*lavalink.Track, error)
+}
+
+// VoiceStates looks up cached voice states.
+type VoiceStates interface {
+	VoiceState(guildID snowflake.ID, userID snowflake.ID) (discord.VoiceState, bool)
+}
+
+// VoiceConnector joins and leaves voice channels.
+type VoiceConnector interface {
+	UpdateVoiceState(ctx context.Context, guildID snowflake.ID, channelID *snowflake.ID, selfMute bool, selfDeaf bool) error
+}
+
+// idleTracker is notified when a guild becomes idle or active again.
+type idleTracker interface {
+	markIdle(guildID snowflake.ID, reason idleReason)
+	// clearIdle clears the given reasons, or all of them when none are given.
+	clearIdle(guildID snowflake.ID, reasons ...idleReason)
+}
+
+// eventPublisher streams the player events caused by the service itself, Lavalink doesn't report pauses.
+type eventPublisher interface {
+	Publish(event PlayerEvent)
+}
+
+// listenerCounter counts the listeners in a voice channel.
+type listenerCounter interface {
+	channelListeners(guildID snowflake.ID, channelID snowflake.ID) int
+}
+
+// MusicService implements the playback logic shared by the slash commands and the control API.
+type MusicService struct {
+	players PlayerProvider
+	loader  TrackLoader
+	states  VoiceStates
+	voice   VoiceConnector
+	queues  *QueueManager
+	idle    idleTracker
+	// capabilities is consulted before loading tracks and applying filters. Unknown capabilities allow everything.
+	capabilities capabilityLookup
+	// policies returns the queue policy of each guild.
+	policies policyLookup
+	// listeners keeps users from moving the bot away from a channel where others are listening.
+	listeners listenerCounter
+	// events receives pause and resume events.
+	events eventPublisher
+
+	// SameChannel requires users to be in the bot's voice channel to control playback, see RequireSameChannel.
+	SameChannel bool
+
+	// AutoPause pauses the player when all listeners leave the voice channel and resumes it when one returns.
+	AutoPause    bool
+	autoPaused   map[snowflake.ID]bool
+	autoPausedMu sync.Mutex
+}
+
+func NewMusicService(players PlayerProvider, loader TrackLoader, states VoiceStates, voice VoiceConnector, queues *QueueManager) *MusicService {
+	return &MusicService{
+		players: players,
+		loader:  loader,
+		states:  states,
+		voice:   voice,
+		queues:  queues,
+		idle:    noopIdleTracker{},
+
+		capabilities: unknownCapabilities{},
+		policies:     noPolicies{},
+		listeners:    noListeners{},
+		events:       noEvents{},
+
+		autoPaused: make(map[snowflake.ID]bool),
+	}
+}
+
+// PlayResult is the outcome of MusicService.Play.
+type PlayResult struct {
+	// Track is the first track that was started or queued.
+	Track lavalink.Track
+	// Tracks are all tracks which were started or queued.
+	Tracks       []lavalink.Track
+	PlaylistName string
+	Search       bool
+	// Queued is set when a track was already playing and the tracks were added to the queue.
+	Queued bool
+	// QueuePosition is the 1-based position of Track in the queue when Queued is set.
+	QueuePosition int
+	// Rejected are the tracks the guild's queue policy did not admit.
+	Rejected []policy.Rejection
+}
+
+// SkipResult is the outcome of MusicService.Skip.
+type SkipResult struct {
+	// Previous is the track which was playing when the skip was requested, if any.
+	Previous *lavalink.Track
+	// Next is the track which was started, if any.
+	Next *lavalink.Track
+	// QueueEmpty is set when no tracks were left in the queue after skipping.
+	QueueEmpty bool
+}
+
+// NowPlaying describes the current track of a player.
+type NowPlaying struct {
+	Track    lavalink.Track
+	Position lavalink.Duration
+}
+
+// QueueState describes a guild's queue and current track.
+type QueueState struct {
+	Current *lavalink.Track
+	Tracks  []lavalink.Track
+	Type    QueueType
+}
+
+// PlayerState is the API representation of a guild's player and queue.
+type PlayerState struct {
+	GuildID   snowflake.ID      `json:"guild_id"`
+	ChannelID *snowflake.ID     `json:"channel_id"`
+	Track     *lavalink.Track   `json:"track"`
+	Position  lavalink.Duration `json:"position"`
+	Paused    bool              `json:"paused"`
+	Volume    int               `json:"volume"`
+	Filters   lavalink.Filters  `json:"filters"`
+	QueueType QueueType         `json:"queue_type"`
+	Queue     []lavalink.Track  `json:"queue"`
+}
+
+type loadResult struct {
+	Tracks       []lavalink.Track
+	PlaylistName string
+	Search       bool
+}
+
+// resolveIdentifier applies the requested search source to identifier, defaulting to YouTube for plain queries.
+func resolveIdentifier(identifier string, source string) string {
+	if source != "" {
+		return lavalink.SearchType(source).Apply(identifier)
+	}
+	if !urlPattern.MatchString(identifier) && !searchPattern.MatchString(identifier) {
+		return lavalink.SearchTypeYouTube.Apply(identifier)
+	}
+	return identifier
+}
+
+// resolveImportIdentifier resolves the identifier of a queue file entry like resolveIdentifier, but returns
+// ErrLocalIdentifier for anything but links and searches, as queue files come from users.
+func resolveImportIdentifier(identifier string) (string, error) {
+	if !urlPattern.MatchString(identifier) && localPattern.MatchString(identifier) {
+		return "", ErrLocalIdentifier
+	}
+	return resolveIdentifier(identifier, ""), nil
+}
+
+func (s *MusicService) existingPlayer(guildID snowflake.ID) (Player, error) {
+	player := s.players.ExistingPlayer(guildID)
+	if player == nil {
+		return nil, ErrNoPlayer
+	}
+	return player, nil
+}
+
+func (s *MusicService) load(ctx context.Context, identifier string) (loadResult, error) {
+	if capabilities, ok := s.capabilities.loaderCapabilities(); ok && !capabilities.SupportsSearch(identifier) {
+		prefix, _, _ := strings.Cut(identifier, ":")
+		return loadResult{}, fmt.Errorf("%w: %s", ErrUnsupportedSource, prefix)
+	}
+	result, err := s.loader.LoadTracks(ctx, identifier)
+	if err != nil {
+		return loadResult{}, err
+	}
+	switch data := result.Data.(type) {
+	case lavalink.Track:
+		return loadResult{Tracks: []lavalink.Track{data}}, nil
+	case lavalink.Playlist:
+		if len(data.Tracks) == 0 {
+			return loadResult{}, ErrNoMatches
+		}
+		return loadResult{Tracks: data.Tracks, PlaylistName: data.Info.Name}, nil
+	case lavalink.Search:
+		if len(data) == 0 {
+			return loadResult{}, ErrNoMatches
+		}
+		return loadResult{Tracks: data[:1], Search: true}, nil
+	case lavalink.Exception:
+		return loadResult{}, data
+	default:
+		return loadResult{}, ErrNoMatches
+	}
+}
+
+// UserVoiceChannel returns the voice channel the user is connected to in the guild.
+func (s *MusicService) UserVoiceChannel(guildID snowflake.ID, userID snowflake.ID) (snowflake.ID, error) {
+	voiceState, ok := s.states.VoiceState(guildID, userID)
+	if !ok || voiceState.ChannelID == nil {
+		return 0, ErrNotInVoice
+	}
+	return *voiceState.ChannelID, nil
+}
+
+// JoinableChannel returns the voice channel of the user for the bot to join. ErrChannelBusy is returned when the
+// bot would have to leave another channel where others are still listening.
+func (s *MusicService) JoinableChannel(guildID snowflake.ID, userID snowflake.ID) (snowflake.ID, error) {
+	channelID, err := s.UserVoiceChannel(guildID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if err = s.CanJoin(guildID, channelID); err != nil {
+		return 0, err
+	}
+	return channelID, nil
+}
+
+// CanJoin returns ErrChannelBusy when joining channelID would make the bot leave another channel where others are
+// still listening.
+func (s *MusicService) CanJoin(guildID snowflake.ID, channelID snowflake.ID) error {
+	player := s.players.ExistingPlayer(guildID)
+	if player == nil || player.ChannelID() == nil || *player.ChannelID() == channelID {
+		return nil
+	}
+	if s.listeners.channelListeners(guildID, *player.ChannelID()) > 0 {
+		return ErrChannelBusy
+	}
+	return nil
+}
+
+// RequireSameChannel returns ErrNotInBotChannel when SameChannel is set and the bot is connected to a voice channel
+// the user is not in.
+func (s *MusicService) RequireSameChannel(guildID snowflake.ID, userID snowflake.ID) error {
+	if !s.SameChannel {
+		return nil
+	}
+	player := s.players.ExistingPlayer(guildID)
+	if player == nil || player.ChannelID() == nil {
+		return nil
+	}
+	if channelID, err := s.UserVoiceChannel(guildID, userID); err != nil || channelID != *player.ChannelID() {
+		return ErrNotInBotChannel
+	}
+	return nil
+}
+
+// Play resolves identifier and connects to channelID. The first track is started when nothing is playing,
+// any remaining tracks are added to the queue.
+func (s *MusicService) Play(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID, requester snowflake.ID, identifier string, source string) (PlayResult, error) {
+	loaded, err := s.load(ctx, resolveIdentifier(identifier, source))
+	if err != nil {
+		return PlayResult{}, err
+	}
+	tracks, rejected, err := s.admit(guildID, requester, loaded.Tracks)
+	if err != nil {
+		return PlayResult{}, err
+	}
+	result := PlayResult{
+		Track:        tracks[0],
+		Tracks:       tracks,
+		PlaylistName: loaded.PlaylistName,
+		Search:       loaded.Search,
+		Rejected:     rejected,
+	}
+
+	if err = s.voice.UpdateVoiceState(ctx, guildID, &channelID, false, false); err != nil {
+		return PlayResult{}, err
+	}
+
+	return s.start(ctx, guildID, result)
+}
+
+// PlayTracks connects to channelID and plays already resolved tracks, such as a saved playlist, like Play does.
+func (s *MusicService) PlayTracks(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID, requester snowflake.ID, tracks []lavalink.Track) (PlayResult, error) {
+	if len(tracks) == 0 {
+		return PlayResult{}, ErrNoTracks
+	}
+	tracks, rejected, err := s.admit(guildID, requester, tracks)
+	if err != nil {
+		return PlayResult{}, err
+	}
+	if err = s.voice.UpdateVoiceState(ctx, guildID, &channelID, false, false); err != nil {
+		return PlayResult{}, err
+	}
+	return s.start(ctx, guildID, PlayResult{Track: tracks[0], Tracks: tracks, Rejected: rejected})
+}
+
+// admit tags tracks with their requester and applies the guild's queue policy to them. A *policy.RejectedError is
+// returned when no track is admitted.
+func (s *MusicService) admit(guildID snowflake.ID, requester snowflake.ID, tracks []lavalink.Track) ([]lavalink.Track, []policy.Rejection, error) {
+	request := policy.Request{
+		Requester: requester,
+		Tracks:    make([]lavalink.Track, len(tracks)),
+		Queue:     s.queues.Get(guildID).Snapshot(),
+	}
+	for i, track := range tracks {
+		request.Tracks[i] = policy.WithRequester(track, requester)
+	}
+	if player := s.players.ExistingPlayer(guildID); player != nil {
+		request.Current = player.Track()
+	}
+	admitted, rejected := s.policies.queuePolicy(guildID).Admit(request)
+	if len(admitted) == 0 {
+		return nil, rejected, &policy.RejectedError{Rejections: rejected}
+	}
+	return admitted, rejected, nil
+}
+
+// start starts the first track of result when nothing is playing and queues the rest.
+func (s *MusicService) start(ctx context.Context, guildID snowflake.ID, result PlayResult) (PlayResult, error) {
+	player := s.players.Player(guildID)
+	queue := s.queues.Get(guildID)
+	if player.Track() != nil {
+		queue.Add(result.Tracks...)
+		result.Queued = true
+		result.QueuePosition = queue.Len() - len(result.Tracks) + 1
+		return result, nil
+	}
+
+	track, err := s.playTrack(ctx, player, result.Tracks[0])
+	if err != nil {
+		return PlayResult{}, err
+	}
+	result.Track = track
+	queue.Add(result.Tracks[1:]...)
+	return result, nil
+}
+
+// playTrack starts track on player and returns the track which was started. A track whose encoding is rejected
+// by the node, e.g. one saved with an older Lavalink version, is resolved again from its URI and retried once.
+func (s *MusicService) playTrack(ctx context.Context, player Player, track lavalink.Track) (lavalink.Track, error) {
+	err := player.Update(ctx, lavalink.WithTrack(track))
+	var lavalinkErr lavalink.Error
+	if !errors.As(err, &lavalinkErr) || lavalinkErr.Status != http.StatusBadRequest {
+		return track, err
+	}
+	refreshed, refreshErr := s.Refresh(ctx, track)
+	if refreshErr != nil {
+		return track, err
+	}
+	return refreshed, player.Update(ctx, lavalink.WithTrack(refreshed))
+}
+
+// Refresh resolves track again from its URI, or by searching its author and title when it has none.
+func (s *MusicService) Refresh(ctx context.Context, track lavalink.Track) (lavalink.Track, error) {
+	loaded, err := s.load(ctx, queuefile.Identifier(track))
+	if err != nil {
+		return lavalink.Track{}, err
+	}
+	return loaded.Tracks[0], nil
+}
+
+// ImportFailure is a queue file entry which could not be resolved.
+type ImportFailure struct {
+	Entry queuefile.Entry
+	Err   error
+}
+
+// Import resolves the entries of a queue file in order. Encoded tracks are decoded by the node, entries without one
+// or whose encoding is rejected are loaded from their identifier like /play does, searching for plain text. Entries
+// which cannot be resolved are returned as failures.
+func (s *MusicService) Import(ctx context.Context, entries []queuefile.Entry) ([]lavalink.Track, []ImportFailure) {
+	var (
+		tracks   []lavalink.Track
+		failures []ImportFailure
+	)
+	for _, entry := range entries {
+		if entry.Track != nil {
+			if track, err := s.loader.DecodeTrack(ctx, entry.Track.Encoded); err == nil {
+				tracks = append(tracks, *track)
+				continue
+			}
+		}
+		identifier, err := resolveImportIdentifier(entry.Identifier)
+		if err != nil {
+			failures = append(failures, ImportFailure{Entry: entry, Err: err})
+			continue
+		}
+		loaded, err := s.load(ctx, identifier)
+		if err != nil {
+			failures = append(failures, ImportFailure{Entry: entry, Err: err})
+			continue
+		}
+		tracks = append(tracks, loaded.Tracks[0])
+	}
+	return tracks, failures
+}
+
+// Resolve resolves identifier into tracks without playing or queueing them.
+func (s *MusicService) Resolve(ctx context.Context, identifier string, source string) ([]lavalink.Track, error) {
+	loaded, err := s.load(ctx, resolveIdentifier(identifier, source))
+	if err != nil {
+		return nil, err
+	}
+	return loaded.Tracks, nil
+}
+
+// Enqueue resolves identifier and adds the tracks the guild's queue policy admits to the end of the queue without
+// starting playback.
+func (s *MusicService) Enqueue(ctx context.Context, guildID snowflake.ID, requester snowflake.ID, identifier string, source string) ([]lavalink.Track, []policy.Rejection, error) {
+	loaded, err := s.load(ctx, resolveIdentifier(identifier, source))
+	if err != nil {
+		return nil, nil, err
+	}
+	tracks, rejected, err := s.admit(guildID, requester, loaded.Tracks)
+	if err != nil {
+		return nil, rejected, err
+	}
+	s.queues.Get(guildID).Add(tracks...)
+	return tracks, rejected, nil
+}
+
+// Skip skips amount tracks in the queue. When a track is playing it is stopped,
+// otherwise the next track in the queue is started.
+func (s *MusicService) Skip(ctx context.Context, guildID snowflake.ID, amount int) (SkipResult, error) {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return SkipResult{}, err
+	}
+	queue := s.queues.Get(guildID)
+
+	track, ok := queue.Skip(amount)
+	if currentTrack := player.Track(); currentTrack != nil {
+		if err = player.Update(ctx, lavalink.WithNullTrack()); err != nil {
+			return SkipResult{}, err
+		}
+		if !ok {
+			s.idle.markIdle(guildID, idleReasonNoTrack)
+		}
+		return SkipResult{Previous: currentTrack, QueueEmpty: !ok}, nil
+	}
+	if !ok {
+		return SkipResult{}, ErrNoTracks
+	}
+
+	if track, err = s.playTrack(ctx, player, track); err != nil {
+		return SkipResult{}, err
+	}
+	return SkipResult{Next: &track}, nil
+}
+
+// TrackEnded starts the next track according to the queue type and returns it.
+// It returns nil when the end reason does not allow a next track or the queue is exhausted.
+func (s *MusicService) TrackEnded(ctx context.Context, guildID snowflake.ID, track lavalink.Track, reason lavalink.TrackEndReason) (*lavalink.Track, error) {
+	if !reason.MayStartNext() {
+		return nil, nil
+	}
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := s.queues.Get(guildID)
+	var (
+		nextTrack lavalink.Track
+		ok        bool
+	)
+	switch queue.Type {
+	case QueueTypeRepeatTrack:
+		nextTrack, ok = track, true
+
+	case QueueTypeRepeatQueue:
+		queue.Add(track)
+		nextTrack, ok = queue.Next()
+
+	default:
+		nextTrack, ok = queue.Next()
+	}
+
+	if !ok {
+		s.idle.markIdle(guildID, idleReasonNoTrack)
+		return nil, nil
+	}
+	if nextTrack, err = s.playTrack(ctx, player, nextTrack); err != nil {
+		return nil, err
+	}
+	return &nextTrack, nil
+}
+
+// Pause pauses or resumes the player. A nil paused toggles the current state. It returns the new state. Pausing
+// starts the paused timeout, and a track paused by AutoPause is no longer resumed automatically.
+func (s *MusicService) Pause(ctx context.Context, guildID snowflake.ID, paused *bool) (bool, error) {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return false, err
+	}
+	target := !player.Paused()
+	if paused != nil {
+		target = *paused
+	}
+	if err = player.Update(ctx, lavalink.WithPaused(target)); err != nil {
+		return player.Paused(), err
+	}
+	s.forgetAutoPause(guildID)
+	if target {
+		s.idle.markIdle(guildID, idleReasonPaused)
+	} else {
+		s.idle.clearIdle(guildID, idleReasonPaused)
+	}
+	s.publishPause(guildID, target)
+	return target, nil
+}
+
+// UpdateListeners records the number of listeners in the bot's voice channel. Without listeners the guild is
+// marked idle and, with AutoPause, a playing track is paused. It is resumed when a listener returns.
+func (s *MusicService) UpdateListeners(ctx context.Context, guildID snowflake.ID, listeners int) error {
+	if listeners > 0 {
+		s.idle.clearIdle(guildID, idleReasonAlone)
+		if !s.takeAutoPause(guildID) {
+			return nil
+		}
+		player := s.players.ExistingPlayer(guildID)
+		if player == nil || !player.Paused() {
+			return nil
+		}
+		if err := player.Update(ctx, lavalink.WithPaused(false)); err != nil {
+			return err
+		}
+		s.publishPause(guildID, false)
+		return nil
+	}
+
+	s.idle.markIdle(guildID, idleReasonAlone)
+	if !s.AutoPause {
+		return nil
+	}
+	player := s.players.ExistingPlayer(guildID)
+	if player == nil || player.Track() == nil || player.Paused() {
+		return nil
+	}
+	if err := player.Update(ctx, lavalink.WithPaused(true)); err != nil {
+		return err
+	}
+	// Pauses made by AutoPause don't start the paused timeout, the guild is already idle without listeners.
+	s.autoPausedMu.Lock()
+	s.autoPaused[guildID] = true
+	s.autoPausedMu.Unlock()
+	s.publishPause(guildID, true)
+	return nil
+}
+
+// RearmIdle arms the idle timers which apply to the guild's player, e.g. after 24/7 mode was disabled. Listeners are
+// tracked separately by UpdateListeners.
+func (s *MusicService) RearmIdle(guildID snowflake.ID) {
+	player := s.players.ExistingPlayer(guildID)
+	switch {
+	case player == nil || player.Track() == nil:
+		s.idle.markIdle(guildID, idleReasonNoTrack)
+	case player.Paused() && !s.isAutoPaused(guildID):
+		s.idle.markIdle(guildID, idleReasonPaused)
+	}
+}
+
+// publishPause publishes that the guild's player was paused or resumed.
+func (s *MusicService) publishPause(guildID snowflake.ID, paused bool) {
+	eventType := PlayerEventResume
+	if paused {
+		eventType = PlayerEventPause
+	}
+	s.events.Publish(PlayerEvent{Type: eventType, GuildID: guildID})
+}
+
+func (s *MusicService) takeAutoPause(guildID snowflake.ID) bool {
+	s.autoPausedMu.Lock()
+	defer s.autoPausedMu.Unlock()
+	paused := s.autoPaused[guildID]
+	delete(s.autoPaused, guildID)
+	return paused
+}
+
+func (s *MusicService) forgetAutoPause(guildID snowflake.ID) {
+	s.takeAutoPause(guildID)
+}
+
+func (s *MusicService) isAutoPaused(guildID snowflake.ID) bool {
+	s.autoPausedMu.Lock()
+	defer s.autoPausedMu.Unlock()
+	return s.autoPaused[guildID]
+}
+
+func (s *MusicService) Stop(ctx context.Context, guildID snowflake.ID) error {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return err
+	}
+	return player.Update(ctx, lavalink.WithNullTrack())
+}
+
+func (s *MusicService) SetVolume(ctx context.Context, guildID snowflake.ID, volume int) error {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return err
+	}
+	return player.Update(ctx, lavalink.WithVolume(volume))
+}
+
+// Seek moves the current track to position, which must lie within the track.
+func (s *MusicService) Seek(ctx context.Context, guildID snowflake.ID, position lavalink.Duration) error {
+	_, err := s.SeekTo(ctx, guildID, timestamp.Position{Offset: time.Duration(position) * time.Millisecond})
+	return err
+}
+
+// SeekTo moves the current track to position and returns the absolute position it resolved to. Relative positions
+// seeking back past the start of the track restart it.
+func (s *MusicService) SeekTo(ctx context.Context, guildID snowflake.ID, position timestamp.Position) (lavalink.Duration, error) {
+	player, track, err := s.seekableTrack(guildID)
+	if err != nil {
+		return 0, err
+	}
+	target := lavalinkDuration(position.Resolve(time.Duration(player.Position()) * time.Millisecond))
+	if target < 0 || target > track.Info.Length {
+		return 0, ErrSeekOutOfRange
+	}
+	return target, player.Update(ctx, lavalink.WithPosition(target))
+}
+
+func (s *MusicService) seekableTrack(guildID snowflake.ID) (Player, lavalink.Track, error) {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return nil, lavalink.Track{}, err
+	}
+	track := player.Track()
+	if track == nil {
+		return nil, lavalink.Track{}, ErrNoTrack
+	}
+	if track.Info.IsStream {
+		return nil, lavalink.Track{}, ErrNotSeekable
+	}
+	return player, *track, nil
+}
+
+// UpdateFilters applies fn to the player's current filters and sends the result to Lavalink.
+func (s *MusicService) UpdateFilters(ctx context.Context, guildID snowflake.ID, fn func(filters *lavalink.Filters)) error {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return err
+	}
+	filters := player.Filters()
+	fn(&filters)
+	if capabilities, ok := s.capabilities.playerCapabilities(guildID); ok {
+		if unsupported := capabilities.UnsupportedFilters(filters); len(unsupported) > 0 {
+			return fmt.Errorf("%w: %s", ErrUnsupportedFilter, strings.Join(unsupported, ", "))
+		}
+	}
+	return player.Update(ctx, lavalink.WithFilters(filters))
+}
+
+func (s *MusicService) SetBassBoost(ctx context.Context, guildID snowflake.ID, enabled bool) error {
+	return s.UpdateFilters(ctx, guildID, func(filters *lavalink.Filters) {
+		if enabled {
+			filters.Equalizer = bassBoost
+		} else {
+			filters.Equalizer = nil
+		}
+	})
+}
+
+func (s *MusicService) NowPlaying(guildID snowflake.ID) (NowPlaying, error) {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return NowPlaying{}, err
+	}
+	track := player.Track()
+	if track == nil {
+		return NowPlaying{}, ErrNoTrack
+	}
+	return NowPlaying{Track: *track, Position: player.Position()}, nil
+}
+
+func (s *MusicService) Queue(guildID snowflake.ID) QueueState {
+	queue := s.queues.Get(guildID)
+	state := QueueState{
+		Tracks: queue.Snapshot(),
+		Type:   queue.Type,
+	}
+	if player := s.players.ExistingPlayer(guildID); player != nil {
+		state.Current = player.Track()
+	}
+	return state
+}
+
+// Tracks returns the current track followed by the queued tracks.
+func (s *MusicService) Tracks(guildID snowflake.ID) []lavalink.Track {
+	state := s.Queue(guildID)
+	var tracks []lavalink.Track
+	if state.Current != nil {
+		tracks = append(tracks, *state.Current)
+	}
+	return append(tracks, state.Tracks...)
+}
+
+func (s *MusicService) Shuffle(guildID snowflake.ID) {
+	s.queues.Get(guildID).Shuffle()
+}
+
+func (s *MusicService) ClearQueue(guildID snowflake.ID) {
+	s.queues.Get(guildID).Clear()
+}
+
+// RemoveFromQueue removes the track at the 0-based index from the queue.
+func (s *MusicService) RemoveFromQueue(guildID snowflake.ID, index int) (lavalink.Track, error) {
+	track, ok := s.queues.Get(guildID).Remove(index)
+	if !ok {
+		return lavalink.Track{}, ErrQueueIndexInvalid
+	}
+	return track, nil
+}
+
+func (s *MusicService) SetQueueType(guildID snowflake.ID, queueType QueueType) error {
+	switch queueType {
+	case QueueTypeNormal, QueueTypeRepeatTrack, QueueTypeRepeatQueue:
+	default:
+		return ErrInvalidQueueType
+	}
+	s.queues.Get(guildID).Type = queueType
+	return nil
+}
+
+// Connect joins the voice channel of the user.
+func (s *MusicService) Connect(ctx context.Context, guildID snowflake.ID, userID snowflake.ID) error {
+	if s.players.ExistingPlayer(guildID) != nil {
+		return ErrAlreadyConnected
+	}
+	channelID, err := s.UserVoiceChannel(guildID, userID)
+	if err != nil {
+		return err
+	}
+	return s.voice.UpdateVoiceState(ctx, guildID, &channelID, false, false)
+}
+
+// Move moves the bot to the voice channel of the user. The player, queue and filters are kept, as only leaving the
+// voice channel tears them down. Unless force is set, ErrChannelBusy is returned when others are still listening in
+// the bot's current channel.
+func (s *MusicService) Move(ctx context.Context, guildID snowflake.ID, userID snowflake.ID, force bool) (snowflake.ID, error) {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return 0, err
+	}
+	channelID, err := s.UserVoiceChannel(guildID, userID)
+	if err != nil {
+		return 0, err
+	}
+	if current := player.ChannelID(); current != nil {
+		if *current == channelID {
+			return 0, ErrAlreadyInChannel
+		}
+		if !force && s.listeners.channelListeners(guildID, *current) > 0 {
+			return 0, ErrChannelBusy
+		}
+	}
+	if err = s.voice.UpdateVoiceState(ctx, guildID, &channelID, false, false); err != nil {
+		return 0, err
+	}
+	return channelID, nil
+}
+
+func (s *MusicService) Disconnect(ctx context.Context, guildID snowflake.ID) error {
+	if _, err := s.existingPlayer(guildID); err != nil {
+		return err
+	}
+	if err := s.voice.UpdateVoiceState(ctx, guildID, nil, false, false); err != nil {
+		return err
+	}
+	s.idle.clearIdle(guildID)
+	s.forgetAutoPause(guildID)
+	return nil
+}
+
+// JoinChannel joins channelID without requiring a user in it, e.g. to rejoin the guild's 24/7 channel.
+func (s *MusicService) JoinChannel(ctx context.Context, guildID snowflake.ID, channelID snowflake.ID) error {
+	return s.voice.UpdateVoiceState(ctx, guildID, &channelID, false, false)
+}
+
+// Leave disconnects from the guild's voice channel without requiring a player, e.g. when the guild went idle.
+func (s *MusicService) Leave(ctx context.Context, guildID snowflake.ID) error {
+	return s.voice.UpdateVoiceState(ctx, guildID, nil, false, false)
+}
+
+// GuildIDs returns the guilds which currently have a player.
+func (s *MusicService) GuildIDs() []snowflake.ID {
+	var guildIDs []snowflake.ID
+	s.players.ForPlayers(func(player Player) {
+		guildIDs = append(guildIDs, player.GuildID())
+	})
+	return guildIDs
+}
+
+func (s *MusicService) State(guildID snowflake.ID) (PlayerState, error) {
+	player, err := s.existingPlayer(guildID)
+	if err != nil {
+		return PlayerState{}, err
+	}
+	queue := s.queues.Get(guildID)
+	return PlayerState{
+		GuildID:   guildID,
+		ChannelID: player.ChannelID(),
+		Track:     player.Track(),
+		Position:  player.Position(),
+		Paused:    player.Paused(),
+		Volume:    player.Volume(),
+		Filters:   player.Filters(),
+		QueueType: queue.Type,
+		Queue:     queue.Snapshot(),
+	}, nil
+}
+
+// policyLookup returns the queue policy of a guild.
+type policyLookup interface {
+	queuePolicy(guildID snowflake.ID) policy.Policy
+}
+
+type noPolicies struct{}
+
+func (noPolicies) queuePolicy(snowflake.ID) policy.Policy { return policy.Policy{} }
+
+type noopIdleTracker struct{}
+
+func (noopIdleTracker) markIdle(snowflake.ID, idleReason)     {}
+func (noopIdleTracker) clearIdle(snowflake.ID, ...idleReason) {}
+
+type noEvents struct{}
+
+func (noEvents) Publish(PlayerEvent) {}
+
+type noListeners struct{}
+
+func (noListeners) channelListeners(snowflake.ID, snowflake.ID) int { return 0 }
+
+// lavalinkPlayers adapts a disgolink.Client to PlayerProvider and TrackLoader.
+type lavalinkPlayers struct {
+	client disgolink.Client
+}
+
+func (l lavalinkPlayers) ExistingPlayer(guildID snowflake.ID) Player {
+	// Return an untyped nil so callers can compare against nil.
+	if player := l.client.ExistingPlayer(guildID); player != nil {
+		return player
+	}
+	return nil
+}
+
+func (l lavalinkPlayers) Player(guildID snowflake.ID) Player {
+	return l.client.Player(guildID)
+}
+
+func (l lavalinkPlayers) ForPlayers(fn func(player Player)) {
+	l.client.ForPlayers(func(player disgolink.Player) {
+		fn(player)
+	})
+}
+
+func (l lavalinkPlayers) LoadTracks(ctx context.Context, identifier string) (*lavalink.LoadResult, error) {
+	node := l.client.BestNode()
+	if node == nil {
+		return nil, ErrNoNode
+	}
+	return node.LoadTracks(ctx, identifier)
+}
+
+func (l lavalinkPlayers) DecodeTrack(ctx context.Context, encoded string) (*lavalink.Track, error) {
+	node := l.client.BestNode()
+	if node == nil {
+		return nil, ErrNoNode
+	}
+	return node.DecodeTrack(ctx, encoded)
+}
@@ -0,0 +1,695 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/policy"
+	"go-discord-music/pkg/timestamp"
+
+	"github.com/Cyb3r-Jak3/common/v5"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePlayer struct {
+	guildID   snowflake.ID
+	channelID *snowflake.ID
+	track     *lavalink.Track
+	paused    bool
+	position  lavalink.Duration
+	volume    int
+	filters   lavalink.Filters
+	updates   []lavalink.PlayerUpdate
+	updateErr error
+}
+
+func (p *fakePlayer) GuildID() snowflake.ID       { return p.guildID }
+func (p *fakePlayer) ChannelID() *snowflake.ID    { return p.channelID }
+func (p *fakePlayer) Track() *lavalink.Track      { return p.track }
+func (p *fakePlayer) Paused() bool                { return p.paused }
+func (p *fakePlayer) Position() lavalink.Duration { return p.position }
+func (p *fakePlayer) Volume() int                 { return p.volume }
+func (p *fakePlayer) Filters() lavalink.Filters   { return p.filters }
+
+func (p *fakePlayer) Update(_ context.Context, opts ...lavalink.PlayerUpdateOpt) error {
+	if p.updateErr != nil {
+		return p.updateErr
+	}
+	update := lavalink.DefaultPlayerUpdate()
+	update.Apply(opts)
+	p.updates = append(p.updates, *update)
+	if update.Track != nil && update.Track.Encoded != nil {
+		if update.Track.Encoded.IsNull() {
+			p.track = nil
+		} else {
+			p.track = &lavalink.Track{Encoded: update.Track.Encoded.Value()}
+			if userData, ok := update.Track.UserData.(lavalink.RawData); ok {
+				p.track.UserData = userData
+			}
+		}
+	}
+	if update.Paused != nil {
+		p.paused = *update.Paused
+	}
+	if update.Volume != nil {
+		p.volume = *update.Volume
+	}
+	if update.Position != nil {
+		p.position = *update.Position
+	}
+	if update.Filters != nil {
+		p.filters = *update.Filters
+	}
+	return nil
+}
+
+type fakePlayers struct {
+	players map[snowflake.ID]*fakePlayer
+}
+
+func newFakePlayers() *fakePlayers {
+	return &fakePlayers{players: make(map[snowflake.ID]*fakePlayer)}
+}
+
+func (f *fakePlayers) ExistingPlayer(guildID snowflake.ID) Player {
+	if player, ok := f.players[guildID]; ok {
+		return player
+	}
+	return nil
+}
+
+func (f *fakePlayers) Player(guildID snowflake.ID) Player {
+	if _, ok := f.players[guildID]; !ok {
+		f.players[guildID] = &fakePlayer{guildID: guildID, volume: 100}
+	}
+	return f.players[guildID]
+}
+
+func (f *fakePlayers) ForPlayers(fn func(player Player)) {
+	for _, player := range f.players {
+		fn(player)
+	}
+}
+
+type fakeLoader struct {
+	results map[string]*lavalink.LoadResult
+}
+
+func (f *fakeLoader) LoadTracks(_ context.Context, identifier string) (*lavalink.LoadResult, error) {
+	if result, ok := f.results[identifier]; ok {
+		return result, nil
+	}
+	return &lavalink.LoadResult{LoadType: lavalink.LoadTypeEmpty, Data: lavalink.Empty{}}, nil
+}
+
+func (f *fakeLoader) DecodeTrack(_ context.Context, encoded string) (*lavalink.Track, error) {
+	for _, result := range f.results {
+		if track, ok := result.Data.(lavalink.Track); ok && track.Encoded == encoded {
+			return &track, nil
+		}
+	}
+	return nil, lavalink.Error{Status: 400, Message: "unknown track"}
+}
+
+type fakeVoiceStates map[snowflake.ID]snowflake.ID
+
+func (f fakeVoiceStates) VoiceState(guildID snowflake.ID, userID snowflake.ID) (discord.VoiceState, bool) {
+	channelID, ok := f[userID]
+	if !ok {
+		return discord.VoiceState{}, false
+	}
+	return discord.VoiceState{GuildID: guildID, UserID: userID, ChannelID: &channelID}, true
+}
+
+type fakeVoice struct {
+	channels map[snowflake.ID]*snowflake.ID
+	err      error
+}
+
+func (f *fakeVoice) UpdateVoiceState(_ context.Context, guildID snowflake.ID, channelID *snowflake.ID, _ bool, _ bool) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.channels[guildID] = channelID
+	return nil
+}
+
+type fakeIdle struct {
+	idle map[snowflake.ID]map[idleReason]bool
+}
+
+func (f *fakeIdle) markIdle(guildID snowflake.ID, reason idleReason) {
+	if f.idle[guildID] == nil {
+		f.idle[guildID] = make(map[idleReason]bool)
+	}
+	f.idle[guildID][reason] = true
+}
+
+func (f *fakeIdle) clearIdle(guildID snowflake.ID, reasons ...idleReason) {
+	if len(reasons) == 0 {
+		delete(f.idle, guildID)
+		return
+	}
+	for _, reason := range reasons {
+		delete(f.idle[guildID], reason)
+	}
+}
+
+const (
+	testGuildID   = snowflake.ID(1)
+	testUserID    = snowflake.ID(2)
+	testChannelID = snowflake.ID(3)
+)
+
+type serviceFixture struct {
+	service *MusicService
+	players *fakePlayers
+	loader  *fakeLoader
+	voice   *fakeVoice
+	idle    *fakeIdle
+	queues  *QueueManager
+}
+
+func newServiceFixture() *serviceFixture {
+	f := &serviceFixture{
+		players: newFakePlayers(),
+		loader:  &fakeLoader{results: make(map[string]*lavalink.LoadResult)},
+		voice:   &fakeVoice{channels: make(map[snowflake.ID]*snowflake.ID)},
+		idle:    &fakeIdle{idle: make(map[snowflake.ID]map[idleReason]bool)},
+		queues:  &QueueManager{queues: make(map[snowflake.ID]*Queue)},
+	}
+	f.service = NewMusicService(f.players, f.loader, fakeVoiceStates{testUserID: testChannelID}, f.voice, f.queues)
+	f.service.idle = f.idle
+	return f
+}
+
+func testTrack(encoded string) lavalink.Track {
+	return lavalink.Track{Encoded: encoded, Info: lavalink.TrackInfo{Title: encoded}}
+}
+
+func Test_MusicService_UserVoiceChannel(t *testing.T) {
+	f := newServiceFixture()
+
+	channelID, err := f.service.UserVoiceChannel(testGuildID, testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, testChannelID, channelID)
+
+	_, err = f.service.UserVoiceChannel(testGuildID, snowflake.ID(99))
+	assert.ErrorIs(t, err, ErrNotInVoice)
+}
+
+func Test_MusicService_Play_StartsTrackWhenIdle(t *testing.T) {
+	f := newServiceFixture()
+	f.loader.results["https://example.com/a"] = &lavalink.LoadResult{Data: testTrack("a")}
+
+	result, err := f.service.Play(context.Background(), testGuildID, testChannelID, 0, "https://example.com/a", "")
+
+	require.NoError(t, err)
+	assert.False(t, result.Queued)
+	assert.Equal(t, "a", result.Track.Encoded)
+	assert.Equal(t, testChannelID, *f.voice.channels[testGuildID])
+	assert.Equal(t, "a", f.players.players[testGuildID].track.Encoded)
+	assert.Equal(t, 0, f.queues.Get(testGuildID).Len())
+}
+
+func Test_MusicService_Play_QueuesWhenPlaying(t *testing.T) {
+	f := newServiceFixture()
+	f.loader.results["https://example.com/b"] = &lavalink.LoadResult{Data: testTrack("b")}
+	current := testTrack("a")
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID, track: &current}
+	f.queues.Get(testGuildID).Add(testTrack("queued"))
+
+	result, err := f.service.Play(context.Background(), testGuildID, testChannelID, 0, "https://example.com/b", "")
+
+	require.NoError(t, err)
+	assert.True(t, result.Queued)
+	assert.Equal(t, 2, result.QueuePosition)
+	assert.Equal(t, "a", f.players.players[testGuildID].track.Encoded)
+	assert.Equal(t, []lavalink.Track{testTrack("queued"), testTrack("b")}, f.queues.Get(testGuildID).Snapshot())
+}
+
+func Test_MusicService_Play_PlaylistQueuesRemainingTracks(t *testing.T) {
+	f := newServiceFixture()
+	f.loader.results["https://example.com/list"] = &lavalink.LoadResult{Data: lavalink.Playlist{
+		Info:   lavalink.PlaylistInfo{Name: "list"},
+		Tracks: []lavalink.Track{testTrack("a"), testTrack("b"), testTrack("c")},
+	}}
+
+	result, err := f.service.Play(context.Background(), testGuildID, testChannelID, 0, "https://example.com/list", "")
+
+	require.NoError(t, err)
+	assert.Equal(t, "list", result.PlaylistName)
+	assert.Len(t, result.Tracks, 3)
+	assert.Equal(t, "a", f.players.players[testGuildID].track.Encoded)
+	assert.Equal(t, []lavalink.Track{testTrack("b"), testTrack("c")}, f.queues.Get(testGuildID).Snapshot())
+}
+
+func Test_MusicService_Play_AppliesSearchSource(t *testing.T) {
+	f := newServiceFixture()
+	f.loader.results["scsearch:song"] = &lavalink.LoadResult{Data: lavalink.Search{testTrack("a"), testTrack("b")}}
+
+	result, err := f.service.Play(context.Background(), testGuildID, testChannelID, 0, "song", string(lavalink.SearchTypeSoundCloud))
+
+	require.NoError(t, err)
+	assert.True(t, result.Search)
+	assert.Equal(t, []lavalink.Track{testTrack("a")}, result.Tracks)
+}
+
+func Test_MusicService_Play_NothingFound(t *testing.T) {
+	f := newServiceFixture()
+
+	_, err := f.service.Play(context.Background(), testGuildID, testChannelID, 0, "song", "")
+
+	assert.ErrorIs(t, err, ErrNoMatches)
+	assert.Empty(t, f.voice.channels)
+}
+
+func Test_MusicService_Skip_StopsCurrentTrack(t *testing.T) {
+	f := newServiceFixture()
+	current := testTrack("a")
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID, track: &current}
+
+	result, err := f.service.Skip(context.Background(), testGuildID, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "a", result.Previous.Encoded)
+	assert.True(t, result.QueueEmpty)
+	assert.Nil(t, f.players.players[testGuildID].track)
+	assert.True(t, f.idle.idle[testGuildID][idleReasonNoTrack])
+}
+
+func Test_MusicService_Skip_WholeQueue(t *testing.T) {
+	f := newServiceFixture()
+	current := testTrack("a")
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID, track: &current}
+	f.queues.Get(testGuildID).Add(testTrack("b"))
+
+	result, err := f.service.Skip(context.Background(), testGuildID, 1)
+
+	require.NoError(t, err)
+	assert.True(t, result.QueueEmpty)
+	assert.Zero(t, f.queues.Get(testGuildID).Len())
+}
+
+func Test_MusicService_Skip_StartsNextTrackWhenStopped(t *testing.T) {
+	f := newServiceFixture()
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID}
+	f.queues.Get(testGuildID).Add(testTrack("a"), testTrack("b"), testTrack("c"))
+
+	result, err := f.service.Skip(context.Background(), testGuildID, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "b", result.Next.Encoded)
+	assert.Equal(t, "b", f.players.players[testGuildID].track.Encoded)
+}
+
+func Test_MusicService_Skip_Errors(t *testing.T) {
+	f := newServiceFixture()
+
+	_, err := f.service.Skip(context.Background(), testGuildID, 1)
+	assert.ErrorIs(t, err, ErrNoPlayer)
+
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID}
+	_, err = f.service.Skip(context.Background(), testGuildID, 1)
+	assert.ErrorIs(t, err, ErrNoTracks)
+}
+
+func Test_MusicService_TrackEnded(t *testing.T) {
+	tests := []struct {
+		name      string
+		queueType QueueType
+		reason    lavalink.TrackEndReason
+		queued    []lavalink.Track
+		expected  *lavalink.Track
+		idle      bool
+	}{
+		{name: "normal starts next", queueType: QueueTypeNormal, reason: lavalink.TrackEndReasonFinished, queued: []lavalink.Track{testTrack("b")}, expected: &lavalink.Track{Encoded: "b"}},
+		{name: "normal empty queue goes idle", queueType: QueueTypeNormal, reason: lavalink.TrackEndReasonFinished, idle: true},
+		{name: "repeat track replays", queueType: QueueTypeRepeatTrack, reason: lavalink.TrackEndReasonFinished, expected: &lavalink.Track{Encoded: "a"}},
+		{name: "repeat queue requeues", queueType: QueueTypeRepeatQueue, reason: lavalink.TrackEndReasonFinished, expected: &lavalink.Track{Encoded: "a"}},
+		{name: "replaced does nothing", queueType: QueueTypeNormal, reason: lavalink.TrackEndReasonReplaced, queued: []lavalink.Track{testTrack("b")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newServiceFixture()
+			f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID}
+			queue := f.queues.Get(testGuildID)
+			queue.Type = tt.queueType
+			queue.Add(tt.queued...)
+
+			next, err := f.service.TrackEnded(context.Background(), testGuildID, testTrack("a"), tt.reason)
+
+			require.NoError(t, err)
+			if tt.expected == nil {
+				assert.Nil(t, next)
+			} else {
+				require.NotNil(t, next)
+				assert.Equal(t, tt.expected.Encoded, next.Encoded)
+				assert.Equal(t, tt.expected.Encoded, f.players.players[testGuildID].track.Encoded)
+			}
+			assert.Equal(t, tt.idle, f.idle.idle[testGuildID][idleReasonNoTrack])
+		})
+	}
+}
+
+func Test_MusicService_Pause_TogglesAndSets(t *testing.T) {
+	f := newServiceFixture()
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID}
+
+	paused, err := f.service.Pause(context.Background(), testGuildID, nil)
+	require.NoError(t, err)
+	assert.True(t, paused)
+
+	paused, err = f.service.Pause(context.Background(), testGuildID, nil)
+	require.NoError(t, err)
+	assert.False(t, paused)
+
+	paused, err = f.service.Pause(context.Background(), testGuildID, common.Ptr(false))
+	require.NoError(t, err)
+	assert.False(t, paused)
+}
+
+func Test_MusicService_PlayerUpdateErrorsArePropagated(t *testing.T) {
+	f := newServiceFixture()
+	updateErr := errors.New("lavalink unavailable")
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID, track: &lavalink.Track{Info: lavalink.TrackInfo{Length: lavalink.Minute}}, updateErr: updateErr}
+
+	assert.ErrorIs(t, f.service.SetVolume(context.Background(), testGuildID, 50), updateErr)
+	assert.ErrorIs(t, f.service.Seek(context.Background(), testGuildID, lavalink.Second), updateErr)
+	assert.ErrorIs(t, f.service.SetBassBoost(context.Background(), testGuildID, true), updateErr)
+	assert.ErrorIs(t, f.service.Stop(context.Background(), testGuildID), updateErr)
+}
+
+func Test_MusicService_Seek(t *testing.T) {
+	f := newServiceFixture()
+	ctx := context.Background()
+	player := &fakePlayer{guildID: testGuildID}
+	f.players.players[testGuildID] = player
+
+	assert.ErrorIs(t, f.service.Seek(ctx, testGuildID, lavalink.Second), ErrNoTrack)
+
+	player.track = &lavalink.Track{Info: lavalink.TrackInfo{Length: lavalink.Minute}}
+	require.NoError(t, f.service.Seek(ctx, testGuildID, 30*lavalink.Second))
+	assert.Equal(t, 30*lavalink.Second, player.position)
+	assert.ErrorIs(t, f.service.Seek(ctx, testGuildID, 2*lavalink.Minute), ErrSeekOutOfRange)
+
+	player.track.Info.IsStream = true
+	assert.ErrorIs(t, f.service.Seek(ctx, testGuildID, lavalink.Second), ErrNotSeekable)
+}
+
+func Test_MusicService_SeekTo(t *testing.T) {
+	f := newServiceFixture()
+	ctx := context.Background()
+	player := &fakePlayer{guildID: testGuildID, track: &lavalink.Track{Info: lavalink.TrackInfo{Length: lavalink.Minute}}, position: 20 * lavalink.Second}
+	f.players.players[testGuildID] = player
+
+	position, err := f.service.SeekTo(ctx, testGuildID, timestamp.Position{Offset: 30 * time.Second, Relative: true})
+	require.NoError(t, err)
+	assert.Equal(t, 50*lavalink.Second, position)
+
+	position, err = f.service.SeekTo(ctx, testGuildID, timestamp.Position{Offset: -time.Minute, Relative: true})
+	require.NoError(t, err)
+	assert.Equal(t, lavalink.Duration(0), position, "rewinding past the start restarts the track")
+
+	_, err = f.service.SeekTo(ctx, testGuildID, timestamp.Position{Offset: 2 * time.Minute, Relative: true})
+	assert.ErrorIs(t, err, ErrSeekOutOfRange)
+	assert.Equal(t, lavalink.Duration(0), player.position)
+
+	position, err = f.service.SeekTo(ctx, testGuildID, timestamp.Position{Offset: 45 * time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, 45*lavalink.Second, position)
+}
+
+func Test_MusicService_SetBassBoost(t *testing.T) {
+	f := newServiceFixture()
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID}
+
+	require.NoError(t, f.service.SetBassBoost(context.Background(), testGuildID, true))
+	assert.Equal(t, bassBoost, f.players.players[testGuildID].filters.Equalizer)
+
+	require.NoError(t, f.service.SetBassBoost(context.Background(), testGuildID, false))
+	assert.Nil(t, f.players.players[testGuildID].filters.Equalizer)
+}
+
+func Test_MusicService_NowPlaying(t *testing.T) {
+	f := newServiceFixture()
+
+	_, err := f.service.NowPlaying(testGuildID)
+	assert.ErrorIs(t, err, ErrNoPlayer)
+
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID}
+	_, err = f.service.NowPlaying(testGuildID)
+	assert.ErrorIs(t, err, ErrNoTrack)
+
+	current := testTrack("a")
+	f.players.players[testGuildID].track = &current
+	f.players.players[testGuildID].position = 5 * lavalink.Second
+	nowPlaying, err := f.service.NowPlaying(testGuildID)
+	require.NoError(t, err)
+	assert.Equal(t, current, nowPlaying.Track)
+	assert.Equal(t, 5*lavalink.Second, nowPlaying.Position)
+}
+
+func Test_MusicService_Queue_WithoutPlayer(t *testing.T) {
+	f := newServiceFixture()
+	f.queues.Get(testGuildID).Add(testTrack("a"))
+
+	state := f.service.Queue(testGuildID)
+
+	assert.Nil(t, state.Current)
+	assert.Equal(t, []lavalink.Track{testTrack("a")}, state.Tracks)
+	assert.Equal(t, QueueTypeNormal, state.Type)
+}
+
+func Test_MusicService_SetQueueType(t *testing.T) {
+	f := newServiceFixture()
+
+	require.NoError(t, f.service.SetQueueType(testGuildID, QueueTypeRepeatQueue))
+	assert.Equal(t, QueueTypeRepeatQueue, f.queues.Get(testGuildID).Type)
+	assert.ErrorIs(t, f.service.SetQueueType(testGuildID, QueueType("bogus")), ErrInvalidQueueType)
+}
+
+func Test_MusicService_ShuffleAndClearWithoutPlayer(t *testing.T) {
+	f := newServiceFixture()
+	f.queues.Get(testGuildID).Add(testTrack("a"), testTrack("b"))
+
+	f.service.Shuffle(testGuildID)
+	assert.ElementsMatch(t, []lavalink.Track{testTrack("a"), testTrack("b")}, f.queues.Get(testGuildID).Snapshot())
+
+	f.service.ClearQueue(testGuildID)
+	assert.Zero(t, f.queues.Get(testGuildID).Len())
+}
+
+func Test_MusicService_ConnectAndDisconnect(t *testing.T) {
+	f := newServiceFixture()
+
+	assert.ErrorIs(t, f.service.Connect(context.Background(), testGuildID, snowflake.ID(99)), ErrNotInVoice)
+	require.NoError(t, f.service.Connect(context.Background(), testGuildID, testUserID))
+	assert.Equal(t, testChannelID, *f.voice.channels[testGuildID])
+
+	assert.ErrorIs(t, f.service.Disconnect(context.Background(), testGuildID), ErrNoPlayer)
+
+	f.players.players[testGuildID] = &fakePlayer{guildID: testGuildID}
+	f.idle.markIdle(testGuildID, idleReasonNoTrack)
+	assert.ErrorIs(t, f.service.Connect(context.Background(), testGuildID, testUserID), ErrAlreadyConnected)
+	require.NoError(t, f.service.Disconnect(context.Background(), testGuildID))
+	assert.Nil(t, f.voice.channels[testGuildID])
+	assert.Empty(t, f.idle.idle[testGuildID])
+}
+
+func Test_MusicService_UpdateListeners(t *testing.T) {
+	f := newServiceFixture()
+	current := testTrack("a")
+	player := &fakePlayer{guildID: testGuildID, track: &current}
+	f.players.players[testGuildID] = player
+
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 0))
+	assert.True(t, f.idle.idle[testGuildID][idleReasonAlone])
+	assert.False(t, player.paused, "players are only paused with AutoPause")
+
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 1))
+	assert.False(t, f.idle.idle[testGuildID][idleReasonAlone])
+	assert.Empty(t, player.updates)
+}
+
+func Test_MusicService_RearmIdle(t *testing.T) {
+	f := newServiceFixture()
+	f.service.RearmIdle(testGuildID)
+	assert.True(t, f.idle.idle[testGuildID][idleReasonNoTrack], "a guild without a player has no track")
+
+	current := testTrack("a")
+	f.players.players[testGuildID+1] = &fakePlayer{guildID: testGuildID + 1, track: &current, paused: true}
+	f.service.RearmIdle(testGuildID + 1)
+	assert.Equal(t, map[idleReason]bool{idleReasonPaused: true}, f.idle.idle[testGuildID+1])
+}
+
+// fakeEvents records published player events.
+type fakeEvents []PlayerEvent
+
+func (e *fakeEvents) Publish(event PlayerEvent) { *e = append(*e, event) }
+
+func Test_MusicService_UpdateListeners_AutoPause(t *testing.T) {
+	f := newServiceFixture()
+	f.service.AutoPause = true
+	events := &fakeEvents{}
+	f.service.events = events
+	current := testTrack("a")
+	player := &fakePlayer{guildID: testGuildID, track: &current}
+	f.players.players[testGuildID] = player
+
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 0))
+	assert.True(t, player.paused)
+	assert.False(t, f.idle.idle[testGuildID][idleReasonPaused], "automatic pauses don't start the paused timeout")
+
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 2))
+	assert.False(t, player.paused)
+	assert.False(t, f.idle.idle[testGuildID][idleReasonAlone])
+	assert.Equal(t, &fakeEvents{
+		{Type: PlayerEventPause, GuildID: testGuildID},
+		{Type: PlayerEventResume, GuildID: testGuildID},
+	}, events)
+}
+
+func Test_MusicService_UpdateListeners_KeepsManualPause(t *testing.T) {
+	f := newServiceFixture()
+	f.service.AutoPause = true
+	current := testTrack("a")
+	player := &fakePlayer{guildID: testGuildID, track: &current}
+	f.players.players[testGuildID] = player
+	paused, err := f.service.Pause(context.Background(), testGuildID, nil)
+	require.NoError(t, err)
+	assert.True(t, paused)
+	assert.True(t, f.idle.idle[testGuildID][idleReasonPaused])
+
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 0))
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 1))
+
+	assert.True(t, player.paused, "a player paused by a user is not resumed")
+	assert.Len(t, player.updates, 1)
+
+	paused, err = f.service.Pause(context.Background(), testGuildID, nil)
+	require.NoError(t, err)
+	assert.False(t, paused)
+	assert.False(t, f.idle.idle[testGuildID][idleReasonPaused])
+}
+
+func Test_MusicService_PauseKeepsAutoPausedTrackPaused(t *testing.T) {
+	f := newServiceFixture()
+	f.service.AutoPause = true
+	current := testTrack("a")
+	player := &fakePlayer{guildID: testGuildID, track: &current}
+	f.players.players[testGuildID] = player
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 0))
+
+	paused := true
+	_, err := f.service.Pause(context.Background(), testGuildID, &paused)
+	require.NoError(t, err)
+	assert.True(t, f.idle.idle[testGuildID][idleReasonPaused])
+
+	require.NoError(t, f.service.UpdateListeners(context.Background(), testGuildID, 1))
+	assert.True(t, player.paused, "a track paused by a user after AutoPause is not resumed")
+}
+
+type staticPolicies policy.Policy
+
+func (p staticPolicies) queuePolicy(snowflake.ID) policy.Policy { return policy.Policy(p) }
+
+func Test_MusicService_Play_AppliesQueuePolicy(t *testing.T) {
+	f := newServiceFixture()
+	f.service.policies = staticPolicies{MaxTracksPerUser: 2}
+	f.loader.results["https://example.com/list"] = &lavalink.LoadResult{Data: lavalink.Playlist{
+		Tracks: []lavalink.Track{testTrack("a"), testTrack("b"), testTrack("c")},
+	}}
+
+	result, err := f.service.Play(context.Background(), testGuildID, testChannelID, testUserID, "https://example.com/list", "")
+	require.NoError(t, err)
+	assert.Len(t, result.Tracks, 2)
+	require.Len(t, result.Rejected, 1)
+	assert.Equal(t, "c", result.Rejected[0].Track.Encoded)
+	queued := f.queues.Get(testGuildID).Snapshot()
+	require.Len(t, queued, 1)
+	assert.Equal(t, testUserID, policy.Requester(queued[0]), "queued tracks remember who requested them")
+
+	_, err = f.service.Play(context.Background(), testGuildID, testChannelID, testUserID, "https://example.com/list", "")
+	assert.ErrorIs(t, err, policy.ErrRejected)
+	assert.Len(t, f.queues.Get(testGuildID).Snapshot(), 1)
+}
+
+func Test_MusicService_Enqueue_AppliesQueuePolicy(t *testing.T) {
+	f := newServiceFixture()
+	f.service.policies = staticPolicies{Blocklist: []string{"b"}}
+	f.loader.results["https://example.com/b"] = &lavalink.LoadResult{Data: testTrack("b")}
+
+	tracks, rejected, err := f.service.Enqueue(context.Background(), testGuildID, testUserID, "https://example.com/b", "")
+
+	assert.ErrorIs(t, err, policy.ErrRejected)
+	assert.Empty(t, tracks)
+	assert.Len(t, rejected, 1)
+	assert.Zero(t, f.queues.Get(testGuildID).Len())
+}
+
+type staticListeners int
+
+func (l staticListeners) channelListeners(snowflake.ID, snowflake.ID) int { return int(l) }
+
+func Test_MusicService_RequireSameChannel(t *testing.T) {
+	f := newServiceFixture()
+	f.service.SameChannel = true
+
+	require.NoError(t, f.service.RequireSameChannel(testGuildID, testUserID), "no player")
+
+	otherChannel := testChannelID + 1
+	f.players.Player(testGuildID).(*fakePlayer).channelID = &otherChannel
+	assert.ErrorIs(t, f.service.RequireSameChannel(testGuildID, testUserID), ErrNotInBotChannel)
+	assert.ErrorIs(t, f.service.RequireSameChannel(testGuildID, snowflake.ID(99)), ErrNotInBotChannel)
+
+	f.service.SameChannel = false
+	assert.NoError(t, f.service.RequireSameChannel(testGuildID, testUserID))
+}
+
+func Test_MusicService_JoinableChannel(t *testing.T) {
+	f := newServiceFixture()
+	otherChannel := testChannelID + 1
+	f.players.Player(testGuildID).(*fakePlayer).channelID = &otherChannel
+
+	channelID, err := f.service.JoinableChannel(testGuildID, testUserID)
+	require.NoError(t, err, "nobody is listening in the bot's channel")
+	assert.Equal(t, testChannelID, channelID)
+
+	f.service.listeners = staticListeners(1)
+	_, err = f.service.JoinableChannel(testGuildID, testUserID)
+	assert.ErrorIs(t, err, ErrChannelBusy)
+
+	f.players.Player(testGuildID).(*fakePlayer).channelID = &channelID
+	_, err = f.service.JoinableChannel(testGuildID, testUserID)
+	assert.NoError(t, err, "the bot is already in the user's channel")
+}
+
+func Test_MusicService_Move(t *testing.T) {
+	f := newServiceFixture()
+	f.service.listeners = staticListeners(1)
+
+	_, err := f.service.Move(context.Background(), testGuildID, testUserID, false)
+	assert.ErrorIs(t, err, ErrNoPlayer)
+
+	otherChannel := testChannelID + 1
+	player := f.players.Player(testGuildID).(*fakePlayer)
+	player.channelID = &otherChannel
+	_, err = f.service.Move(context.Background(), testGuildID, testUserID, false)
+	assert.ErrorIs(t, err, ErrChannelBusy)
+
+	channelID, err := f.service.Move(context.Background(), testGuildID, testUserID, true)
+	require.NoError(t, err)
+	assert.Equal(t, testChannelID, channelID)
+	assert.Equal(t, &channelID, f.voice.channels[testGuildID])
+
+	player.channelID = &channelID
+	_, err = f.service.Move(context.Background(), testGuildID, testUserID, true)
+	assert.ErrorIs(t, err, ErrAlreadyInChannel)
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgo/sharding"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// Sharding configures the shards the bot connects with. Guilds are spread over the shards by Discord, so a bot in
+// more guilds than a single gateway connection may hold can run its shards in one process or split them over several.
+type Sharding struct {
+	// Count is the total number of shards across all processes. 0 uses the count recommended by Discord.
+	Count int
+	// IDs are the shards run by this process. Empty runs all of them.
+	IDs []int
+}
+
+// validate checks that the shard IDs are within the shard count.
+func (s Sharding) validate() error {
+	if s.Count < 0 {
+		return fmt.Errorf("invalid shard count %d", s.Count)
+	}
+	if len(s.IDs) > 0 && s.Count == 0 {
+		return fmt.Errorf("a shard count is required when selecting shard IDs")
+	}
+	for _, id := range s.IDs {
+		if id < 0 || id >= s.Count {
+			return fmt.Errorf("shard ID %d is not within the shard count %d", id, s.Count)
+		}
+	}
+	return nil
+}
+
+// configOpts returns the shard manager options. disgo defaults to the shard count recommended by Discord.
+func (s Sharding) configOpts(gatewayOpts ...gateway.ConfigOpt) []sharding.ConfigOpt {
+	opts := []sharding.ConfigOpt{sharding.WithGatewayConfigOpts(gatewayOpts...)}
+	if s.Count == 0 {
+		return opts
+	}
+	ids := s.IDs
+	if len(ids) == 0 {
+		ids = make([]int, s.Count)
+		for i := range ids {
+			ids[i] = i
+		}
+	}
+	return append(opts, sharding.WithShardCount(s.Count), sharding.WithShardIDs(ids...))
+}
+
+// gatewayOpts returns the options connecting the client with a single gateway or, when configured, with shards.
+func (b *Bot) gatewayOpts() []bot.ConfigOpt {
+	gatewayOpts := []gateway.ConfigOpt{gateway.WithIntents(gateway.IntentGuilds, gateway.IntentGuildVoiceStates)}
+	if b.Sharding == nil {
+		return []bot.ConfigOpt{bot.WithGatewayConfigOpts(gatewayOpts...)}
+	}
+	return []bot.ConfigOpt{bot.WithShardManagerConfigOpts(b.Sharding.configOpts(gatewayOpts...)...)}
+}
+
+// openGateway connects to Discord with the single gateway or all shards of this process.
+func (b *Bot) openGateway(ctx context.Context) error {
+	if b.Client.HasShardManager() {
+		return b.Client.OpenShardManager(ctx)
+	}
+	return b.Client.OpenGateway(ctx)
+}
+
+// gateways returns the gateway connections of the bot keyed by shard ID. Without sharding the gateway is shard 0.
+func (b *Bot) gateways() map[int]gateway.Gateway {
+	if b.Client.HasShardManager() {
+		return b.Client.ShardManager().Shards()
+	}
+	if b.Client.HasGateway() {
+		return map[int]gateway.Gateway{0: b.Client.Gateway()}
+	}
+	return nil
+}
+
+// shardIDs returns the IDs of the shards of this process in order.
+func (b *Bot) shardIDs() []int {
+	return slices.Sorted(maps.Keys(b.gateways()))
+}
+
+// applicationIDFromToken returns the ID of the application a bot token belongs to, which is encoded in its first part.
+// It is needed before the Discord client exists, as the client is only created once the options are parsed.
+func applicationIDFromToken(token string) (snowflake.ID, error) {
+	encoded, _, _ := strings.Cut(token, ".")
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return 0, fmt.Errorf("invalid bot token: %w", err)
+	}
+	id, err := snowflake.Parse(string(decoded))
+	if err != nil {
+		return 0, fmt.Errorf("invalid bot token: %w", err)
+	}
+	return id, nil
+}
+
+// clientVoice gives MusicService access to the voice states and voice connections of the Discord client. The
+// client is looked up on every call as it is created after the music service. Voice state updates are sent on the
+// shard of the guild.
+type clientVoice struct {
+	bot *Bot
+}
+
+func (v clientVoice) VoiceState(guildID snowflake.ID, userID snowflake.ID) (discord.VoiceState, bool) {
+	return v.bot.Client.Caches().VoiceState(guildID, userID)
+}
+
+func (v clientVoice) UpdateVoiceState(ctx context.Context, guildID snowflake.ID, channelID *snowflake.ID, selfMute bool, selfDeaf bool) error {
+	return v.bot.Client.UpdateVoiceState(ctx, guildID, channelID, selfMute, selfDeaf)
+}
+
+// debugMaxShards is the number of shards listed by /debug, an embed field can't hold many more.
+const debugMaxShards = 16
+
+// gatewaysString lists the status and latency of each shard for /debug, marking the shard of guildID.
+func (b *Bot) gatewaysString(guildID *snowflake.ID) string {
+	gateways := b.gateways()
+	if len(gateways) == 0 {
+		return "Not connected"
+	}
+	var list strings.Builder
+	for i, shardID := range slices.Sorted(maps.Keys(gateways)) {
+		if i == debugMaxShards {
+			fmt.Fprintf(&list, "... and %d more shards", len(gateways)-debugMaxShards)
+			break
+		}
+		gw := gateways[shardID]
+		fmt.Fprintf(&list, "Shard `%d/%d`: `%s` latency `%s`", shardID, gw.ShardCount(), gw.Status(), gw.Latency().Round(time.Millisecond))
+		if guildID != nil && sharding.ShardIDByGuild(*guildID, gw.ShardCount()) == shardID {
+			list.WriteString(" (this server)")
+		}
+		list.WriteString("\n")
+	}
+	return list.String()
+}
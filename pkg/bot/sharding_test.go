@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"testing"
+
+	"go-discord-music/pkg/discordtest"
+
+	"github.com/disgoorg/disgo/sharding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ShardingValidate(t *testing.T) {
+	assert.NoError(t, Sharding{}.validate())
+	assert.NoError(t, Sharding{Count: 4, IDs: []int{0, 3}}.validate())
+	assert.Error(t, Sharding{Count: -1}.validate())
+	assert.Error(t, Sharding{IDs: []int{0}}.validate(), "shard IDs need a shard count")
+	assert.Error(t, Sharding{Count: 2, IDs: []int{2}}.validate())
+}
+
+func Test_ShardingConfigOpts(t *testing.T) {
+	config := sharding.DefaultConfig()
+	config.Apply(Sharding{Count: 3}.configOpts())
+	assert.Equal(t, 3, config.ShardCount)
+	assert.Len(t, config.ShardIDs, 3, "all shards run when none are selected")
+
+	config = sharding.DefaultConfig()
+	config.Apply(Sharding{Count: 4, IDs: []int{1, 2}}.configOpts())
+	assert.Equal(t, 4, config.ShardCount)
+	assert.Len(t, config.ShardIDs, 2)
+}
+
+func Test_ApplicationIDFromToken(t *testing.T) {
+	id, err := applicationIDFromToken(discordtest.Token(testBotUserID))
+	require.NoError(t, err)
+	assert.Equal(t, testBotUserID, id)
+
+	_, err = applicationIDFromToken("not a token")
+	assert.Error(t, err)
+}
+
+func Test_Gateway_DebugListsShards(t *testing.T) {
+	f := newGatewayFixture(t)
+	guildID := testGuildID
+
+	assert.Equal(t, []int{0}, f.bot.shardIDs())
+	assert.Contains(t, f.bot.gatewaysString(&guildID), "Shard `0/1`")
+	assert.Contains(t, f.bot.gatewaysString(&guildID), "(this server)")
+	assert.NotContains(t, f.bot.gatewaysString(nil), "(this server)")
+}
@@ -0,0 +1,101 @@
+package bot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/json"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// stageTopicMaxLength is the longest stage topic Discord accepts.
+const stageTopicMaxLength = 120
+
+// isStageChannel reports whether channelID is a stage channel.
+func (b *Bot) isStageChannel(channelID snowflake.ID) bool {
+	channel, ok := b.Client.Caches().Channel(channelID)
+	return ok && channel.Type() == discord.ChannelTypeGuildStageVoice
+}
+
+// joinStage makes the bot a speaker after it joined a stage channel, where it starts out in the audience. Without
+// the permission to become a speaker it requests to speak instead, so a stage moderator can invite it.
+func (b *Bot) joinStage(state discord.VoiceState) {
+	if state.ChannelID == nil || !state.Suppress || state.RequestToSpeakTimestamp != nil || !b.isStageChannel(*state.ChannelID) {
+		return
+	}
+	suppress := false
+	err := b.Client.Rest().UpdateCurrentUserVoiceState(state.GuildID, discord.CurrentUserVoiceStateUpdate{
+		ChannelID: state.ChannelID,
+		Suppress:  &suppress,
+	})
+	if err != nil {
+		b.logger.Warnf("error becoming a speaker in stage channel %s of guild %s, requesting to speak instead: %v", *state.ChannelID, state.GuildID, err)
+		err = b.Client.Rest().UpdateCurrentUserVoiceState(state.GuildID, discord.CurrentUserVoiceStateUpdate{
+			ChannelID:               state.ChannelID,
+			RequestToSpeakTimestamp: json.NewNullablePtr(time.Now()),
+		})
+		if err != nil {
+			b.logger.Errorf("error requesting to speak in stage channel %s of guild %s: %v", *state.ChannelID, state.GuildID, err)
+			return
+		}
+	}
+	b.logger.Infof("joined stage channel %s in guild %s", *state.ChannelID, state.GuildID)
+
+	if nowPlaying, err := b.Music.NowPlaying(state.GuildID); err == nil {
+		b.updateStageTopic(state.GuildID, nowPlaying.Track)
+	}
+}
+
+// updateStageTopic sets the topic of the stage the bot is in to track, starting the stage when it isn't live yet.
+// It does nothing unless StageTopic is enabled.
+func (b *Bot) updateStageTopic(guildID snowflake.ID, track lavalink.Track) {
+	if !b.StageTopic {
+		return
+	}
+	channelID := b.botChannel(guildID)
+	if channelID == nil || !b.isStageChannel(*channelID) {
+		return
+	}
+	topic := stageTopic(track)
+
+	var err error
+	if instance, ok := b.stageInstance(guildID, *channelID); ok {
+		if instance.Topic == topic {
+			return
+		}
+		_, err = b.Client.Rest().UpdateStageInstance(*channelID, discord.StageInstanceUpdate{Topic: &topic})
+	} else {
+		_, err = b.Client.Rest().CreateStageInstance(discord.StageInstanceCreate{ChannelID: *channelID, Topic: topic})
+	}
+	if err != nil {
+		b.logger.Errorf("error updating the topic of stage channel %s in guild %s: %v", *channelID, guildID, err)
+	}
+}
+
+// stageInstance returns the live stage of a stage channel.
+func (b *Bot) stageInstance(guildID snowflake.ID, channelID snowflake.ID) (discord.StageInstance, bool) {
+	var (
+		instance discord.StageInstance
+		found    bool
+	)
+	b.Client.Caches().StageInstanceForEach(guildID, func(stageInstance discord.StageInstance) {
+		if stageInstance.ChannelID == channelID {
+			instance, found = stageInstance, true
+		}
+	})
+	return instance, found
+}
+
+// stageTopic returns the stage topic announcing track, shortened to the length Discord accepts.
+func stageTopic(track lavalink.Track) string {
+	topic := fmt.Sprintf("Now playing: %s", track.Info.Title)
+	if track.Info.Author != "" {
+		topic += " by " + track.Info.Author
+	}
+	if runes := []rune(topic); len(runes) > stageTopicMaxLength {
+		topic = string(runes[:stageTopicMaxLength-1]) + "…"
+	}
+	return topic
+}
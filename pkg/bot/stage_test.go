@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-discord-music/pkg/discordtest"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Gateway_StageSpeakerAndTopic(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.StageTopic = true
+	f.replay("stage_channel")
+	f.replay("bot_joined_stage")
+
+	f.playTrack(t)
+	f.replay("stage_started")
+	f.bot.updateStageTopic(testGuildID, lavalink.Track{Info: lavalink.TrackInfo{Title: "b", Author: "Artist"}})
+
+	f.discord.AssertGolden("stage_speaker_topic")
+}
+
+func Test_Gateway_StageRequestsToSpeak(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.discord.Transport.Respond(http.MethodPatch, "/guilds/1/voice-states/@me", discordtest.Response{
+		Status: http.StatusForbidden,
+		Body:   map[string]any{"code": 50013, "message": "Missing Permissions"},
+	})
+	f.replay("stage_channel")
+
+	f.replay("bot_joined_stage")
+
+	requests := f.discord.Transport.Requests()
+	require.Len(t, requests, 2)
+	assert.JSONEq(t, `{"channel_id":"3","suppress":false}`, string(requests[0].Body))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(requests[1].Body, &body))
+	assert.Equal(t, "3", body["channel_id"])
+	assert.NotEmpty(t, body["request_to_speak_timestamp"])
+}
+
+func Test_StageTopic(t *testing.T) {
+	assert.Equal(t, "Now playing: a", stageTopic(lavalink.Track{Info: lavalink.TrackInfo{Title: "a"}}))
+	assert.Equal(t, "Now playing: a by b", stageTopic(lavalink.Track{Info: lavalink.TrackInfo{Title: "a", Author: "b"}}))
+
+	topic := stageTopic(lavalink.Track{Info: lavalink.TrackInfo{Title: strings.Repeat("é", 200)}})
+	assert.Len(t, []rune(topic), stageTopicMaxLength)
+	assert.True(t, strings.HasSuffix(topic, "…"))
+}
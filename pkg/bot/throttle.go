@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+
+	"go-discord-music/pkg/ratelimit"
+
+	"github.com/sirupsen/logrus"
+)
+
+// throttledValue is the state of one key of throttledUpdates.
+type throttledValue[V comparable] struct {
+	// sent is the value last sent, wanted the value to send.
+	sent   V
+	wanted V
+	// flush is armed while an update waits for the rate limit.
+	flush stopper
+}
+
+// throttledUpdates sends the value of each key, such as the status of a voice channel, within a rate limit. Updates
+// beyond the limit are delayed and coalesced, so only the latest value of a key is sent once the limit allows it.
+// The zero value of V is the initial value of every key.
+type throttledUpdates[K comparable, V comparable] struct {
+	mu      sync.Mutex
+	clock   clock
+	limit   ratelimit.Limit
+	limiter *ratelimit.Limiter
+	// name describes a key in logs, e.g. "the status of voice channel".
+	name    string
+	send    func(key K, value V) error
+	logger  *logrus.Logger
+	values  map[K]*throttledValue[V]
+	stopped bool
+}
+
+func newThrottledUpdates[K comparable, V comparable](c clock, limit ratelimit.Limit, name string, send func(key K, value V) error, logger *logrus.Logger) *throttledUpdates[K, V] {
+	return &throttledUpdates[K, V]{
+		clock:   c,
+		limit:   limit,
+		limiter: ratelimit.New(c.Now),
+		name:    name,
+		send:    send,
+		logger:  logger,
+		values:  make(map[K]*throttledValue[V]),
+	}
+}
+
+// set sends value for key, unless it was sent last. When the key is rate limited the value is sent once the limit
+// allows it, unless it is replaced before.
+func (t *throttledUpdates[K, V]) set(key K, value V) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	state, ok := t.values[key]
+	if !ok {
+		state = &throttledValue[V]{}
+		t.values[key] = state
+	}
+	state.wanted = value
+	if state.flush != nil {
+		return
+	}
+	t.update(key, state)
+}
+
+// flush sends the wanted value of key after it waited for the rate limit.
+func (t *throttledUpdates[K, V]) flush(key K) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.values[key]
+	if t.stopped || !ok {
+		return
+	}
+	state.flush = nil
+	t.update(key, state)
+}
+
+// update sends the wanted value of a key if it changed. It is called with mu held, which keeps the updates of a key
+// in order. Keys are forgotten once they are back at the zero value.
+func (t *throttledUpdates[K, V]) update(key K, state *throttledValue[V]) {
+	var zero V
+	if state.wanted != state.sent {
+		allowed, retryAfter := t.limiter.Allow(fmt.Sprint(key), t.limit)
+		if !allowed {
+			state.flush = t.clock.AfterFunc(retryAfter, func() { t.flush(key) })
+			return
+		}
+		if err := t.send(key, state.wanted); err != nil {
+			t.logger.Errorf("error updating %s %v: %v", t.name, key, err)
+			return
+		}
+		state.sent = state.wanted
+	}
+	if state.sent == zero {
+		delete(t.values, key)
+	}
+}
+
+// stop cancels all delayed updates. Later updates are ignored.
+func (t *throttledUpdates[K, V]) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	for _, state := range t.values {
+		if state.flush != nil {
+			state.flush.Stop()
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"go-discord-music/pkg/ratelimit"
+
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type sentUpdate struct {
+	key   snowflake.ID
+	value string
+}
+
+func Test_ThrottledUpdatesCoalesce(t *testing.T) {
+	clock := newFakeClock()
+	limit := ratelimit.Limit{Burst: 2, Per: 30 * time.Second}
+	var sent []sentUpdate
+	updates := newThrottledUpdates(clock, limit, "test", func(key snowflake.ID, value string) error {
+		sent = append(sent, sentUpdate{key, value})
+		return nil
+	}, logrus.New())
+
+	updates.set(testChannelID, "a")
+	updates.set(testChannelID, "a")
+	updates.set(testChannelID, "b")
+	updates.set(testChannelID, "c")
+	updates.set(testChannelID, "d")
+	updates.set(testChannelID+1, "other")
+	assert.Equal(t, []sentUpdate{{testChannelID, "a"}, {testChannelID, "b"}, {testChannelID + 1, "other"}}, sent)
+
+	clock.Advance(limit.Per / 2)
+	assert.Equal(t, sentUpdate{testChannelID, "d"}, sent[len(sent)-1], "only the latest value is sent")
+	assert.Len(t, sent, 4)
+
+	updates.set(testChannelID, "")
+	clock.Advance(limit.Per / 2)
+	assert.Equal(t, sentUpdate{testChannelID, ""}, sent[len(sent)-1])
+	assert.NotContains(t, updates.values, testChannelID)
+}
+
+func Test_ThrottledUpdatesStop(t *testing.T) {
+	clock := newFakeClock()
+	limit := ratelimit.Limit{Burst: 2, Per: 30 * time.Second}
+	sent := 0
+	updates := newThrottledUpdates(clock, limit, "test", func(snowflake.ID, string) error {
+		sent++
+		return nil
+	}, logrus.New())
+
+	for _, value := range []string{"a", "b", "c"} {
+		updates.set(testChannelID, value)
+	}
+	updates.stop()
+	clock.Advance(limit.Per)
+	updates.set(testChannelID, "d")
+
+	assert.Equal(t, 2, sent)
+}
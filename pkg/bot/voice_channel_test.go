@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Gateway_ControlsRequireBotChannel(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.replay("bot_joined")
+	f.replay("user_other_channel")
+
+	f.replay("pause_without_player")
+
+	f.discord.AssertGolden("pause_other_channel")
+	player, ok := f.server.Player(testGuildID)
+	assert.True(t, ok)
+	assert.False(t, player.Paused)
+}
+
+func Test_Gateway_PlayKeepsBotWithListeners(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.replay("bot_joined")
+	f.replay("listener_joined")
+
+	f.replay("play_other_channel")
+
+	f.discord.AssertGolden("play_channel_busy")
+}
+
+func Test_Gateway_BotDraggedKeepsSession(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.replay("bot_joined")
+	f.bot.Queues.Get(testGuildID).Add(testTrack("queued"))
+	require.NoError(t, f.bot.Music.SetBassBoost(context.Background(), testGuildID, true))
+	events, cancel := f.bot.playerEvents.Subscribe()
+	defer cancel()
+
+	f.replay("bot_dragged")
+
+	event := waitForEvent(t, events, PlayerEventMoved)
+	assert.Equal(t, snowflake.ID(4), *event.ChannelID)
+	player, ok := f.server.Player(testGuildID)
+	require.True(t, ok)
+	assert.Equal(t, "a", f.server.CurrentTrack(testGuildID))
+	assert.Equal(t, bassBoost, player.Filters.Equalizer)
+	assert.Equal(t, lavalink.VoiceState{Token: "moved-voice-token", Endpoint: "voice-2.example.com", SessionID: "bot-session"}, player.Voice)
+	assert.Equal(t, 1, f.bot.Queues.Get(testGuildID).Len())
+	assert.Equal(t, snowflake.ID(4), *f.bot.botChannel(testGuildID))
+
+	f.replay("bot_left")
+
+	assert.Nil(t, f.bot.botChannel(testGuildID))
+	assert.Zero(t, f.bot.Queues.Get(testGuildID).Len())
+}
+
+func Test_Gateway_MoveHere(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.replay("bot_joined")
+	f.replay("user_other_channel")
+
+	f.replay("move_here")
+
+	f.discord.AssertGolden("move_here")
+}
+
+func Test_Gateway_MoveHereKeepsBotWithListeners(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.playTrack(t)
+	f.replay("bot_joined")
+	f.replay("listener_stayed")
+	f.replay("user_other_channel")
+
+	f.replay("move_here")
+	f.replay("move_here_moderator")
+
+	f.discord.AssertGolden("move_here_busy")
+}
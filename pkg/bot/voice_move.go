@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// botChannel returns the voice channel the guild's player is connected to, nil when there is none.
+func (b *Bot) botChannel(guildID snowflake.ID) *snowflake.ID {
+	player := b.Lavalink.ExistingPlayer(guildID)
+	if player == nil {
+		return nil
+	}
+	return player.ChannelID()
+}
+
+// onBotMoved is called when the bot changed voice channels, either through /move-here or by a moderator dragging it.
+// Lavalink keeps the player across the voice server update that follows, so the track, position and filters carry
+// over and only the listeners of the new channel need to be counted again, which the voice state update handler does.
+func (b *Bot) onBotMoved(guildID snowflake.ID, from snowflake.ID, to snowflake.ID) {
+	b.logger.Infof("moved from voice channel %s to %s in guild %s", from, to, guildID)
+	b.playerEvents.Publish(PlayerEvent{Type: PlayerEventMoved, GuildID: guildID, ChannelID: &to})
+	b.clearVoiceStatus(from)
+	if nowPlaying, err := b.Music.NowPlaying(guildID); err == nil {
+		b.updateVoiceStatus(guildID, &nowPlaying.Track)
+	}
+}
+
+// moveHere moves the bot to the user's voice channel. Members who may move members can take the bot away from
+// others who are still listening.
+func (b *Bot) moveHere(ctx context.Context, event *events.ApplicationCommandInteractionCreate, _ discord.SlashCommandInteractionData) error {
+	member := event.Member()
+	force := member != nil && member.Permissions.Has(discord.PermissionMoveMembers)
+	channelID, err := b.Music.Move(ctx, *event.GuildID(), event.User().ID, force)
+	if err != nil {
+		return serviceError("moving the player", err)
+	}
+
+	return event.CreateMessage(discord.MessageCreate{
+		Content: fmt.Sprintf("Moved to <#%s>", channelID),
+	})
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-discord-music/pkg/ratelimit"
+
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// voiceStatusMaxLength is the longest voice channel status Discord accepts.
+const voiceStatusMaxLength = 500
+
+// voiceStatusLimit limits how often the status of a voice channel is set. Updates beyond it are delayed and
+// coalesced, so skipping through the queue only sends the status of the track that ends up playing.
+var voiceStatusLimit = ratelimit.Limit{Burst: 2, Per: 30 * time.Second}
+
+// setVoiceStatusEndpoint sets the status of a voice channel. disgo does not support it yet.
+var setVoiceStatusEndpoint = rest.NewEndpoint(http.MethodPut, "/channels/{channel.id}/voice-status")
+
+type voiceStatusUpdate struct {
+	Status string `json:"status"`
+}
+
+// newVoiceStatuses returns the throttled updates setting voice channel statuses with send, within voiceStatusLimit.
+func newVoiceStatuses(c clock, send func(channelID snowflake.ID, status string) error, logger *logrus.Logger) *throttledUpdates[snowflake.ID, string] {
+	return newThrottledUpdates(c, voiceStatusLimit, "the status of voice channel", send, logger)
+}
+
+// sendVoiceStatus sets the status of a voice channel on Discord.
+func (b *Bot) sendVoiceStatus(channelID snowflake.ID, status string) error {
+	return b.Client.Rest().Do(setVoiceStatusEndpoint.Compile(nil, channelID), voiceStatusUpdate{Status: status}, nil)
+}
+
+// updateVoiceStatus sets the status of the bot's voice channel in the guild to track, or clears it when track is nil.
+// It does nothing unless VoiceStatus is enabled.
+func (b *Bot) updateVoiceStatus(guildID snowflake.ID, track *lavalink.Track) {
+	if !b.VoiceStatus {
+		return
+	}
+	channelID := b.botChannel(guildID)
+	if channelID == nil {
+		return
+	}
+	status := ""
+	if track != nil {
+		status = voiceStatusText(*track)
+	}
+	b.voiceStatuses.set(*channelID, status)
+}
+
+// clearVoiceStatus clears the status of a voice channel the bot left.
+func (b *Bot) clearVoiceStatus(channelID snowflake.ID) {
+	if !b.VoiceStatus {
+		return
+	}
+	b.voiceStatuses.set(channelID, "")
+}
+
+// voiceStatusText returns the voice channel status announcing track, shortened to the length Discord accepts.
+func voiceStatusText(track lavalink.Track) string {
+	status := fmt.Sprintf("Now playing: %s", track.Info.Title)
+	if track.Info.Author != "" {
+		status += " — " + track.Info.Author
+	}
+	if runes := []rune(status); len(runes) > voiceStatusMaxLength {
+		status = string(runes[:voiceStatusMaxLength-1]) + "…"
+	}
+	return status
+}
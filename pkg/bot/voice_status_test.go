@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_VoiceStatusesCoalesceRateLimitedUpdates(t *testing.T) {
+	clock := newFakeClock()
+	var sent []sentUpdate
+	statuses := newVoiceStatuses(clock, func(channelID snowflake.ID, status string) error {
+		sent = append(sent, sentUpdate{channelID, status})
+		return nil
+	}, logrus.New())
+
+	statuses.set(testChannelID, "a")
+	statuses.set(testChannelID, "b")
+	statuses.set(testChannelID, "c")
+	statuses.set(testChannelID, "d")
+	assert.Equal(t, []sentUpdate{{testChannelID, "a"}, {testChannelID, "b"}}, sent)
+
+	clock.Advance(voiceStatusLimit.Per / 2)
+	assert.Equal(t, []sentUpdate{{testChannelID, "a"}, {testChannelID, "b"}, {testChannelID, "d"}}, sent, "only the latest status is sent")
+
+	statuses.set(testChannelID, "")
+	clock.Advance(voiceStatusLimit.Per / 2)
+	assert.Equal(t, sentUpdate{testChannelID, ""}, sent[len(sent)-1])
+	assert.NotContains(t, statuses.values, testChannelID, "cleared channels are forgotten")
+}
+
+func Test_VoiceStatusesStop(t *testing.T) {
+	clock := newFakeClock()
+	sent := 0
+	statuses := newVoiceStatuses(clock, func(snowflake.ID, string) error {
+		sent++
+		return nil
+	}, logrus.New())
+
+	for _, status := range []string{"a", "b", "c"} {
+		statuses.set(testChannelID, status)
+	}
+	statuses.stop()
+	clock.Advance(voiceStatusLimit.Per)
+	statuses.set(testChannelID, "d")
+
+	assert.Equal(t, 2, sent)
+}
+
+func Test_Gateway_VoiceStatusFollowsPlayback(t *testing.T) {
+	f := newGatewayFixture(t)
+	f.bot.VoiceStatus = true
+	f.replay("bot_joined")
+
+	f.playTrack(t)
+	f.replay("bot_left")
+
+	f.discord.AssertGolden("voice_status")
+}
+
+func Test_VoiceStatusText(t *testing.T) {
+	assert.Equal(t, "Now playing: a", voiceStatusText(lavalink.Track{Info: lavalink.TrackInfo{Title: "a"}}))
+	assert.Equal(t, "Now playing: a — b", voiceStatusText(lavalink.Track{Info: lavalink.TrackInfo{Title: "a", Author: "b"}}))
+
+	status := voiceStatusText(lavalink.Track{Info: lavalink.TrackInfo{Title: strings.Repeat("é", 600)}})
+	assert.Len(t, []rune(status), voiceStatusMaxLength)
+	assert.True(t, strings.HasSuffix(status, "…"))
+}
@@ -0,0 +1,353 @@
+// Package discordtest provides a harness for testing disgo based bots without connecting to Discord.
+//
+// The harness replaces the gateway and the REST transport of a disgo client. Gateway events can be
+// injected directly or replayed from recorded fixture files, and every REST request and gateway
+// payload the bot sends is captured so it can be compared against golden files.
+package discordtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgo/bot"
+	"github.com/disgoorg/disgo/gateway"
+	"github.com/disgoorg/disgo/rest"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+var update = flag.Bool("update", false, "update discordtest golden files")
+
+// Token returns a syntactically valid bot token for applicationID.
+func Token(applicationID snowflake.ID) string {
+	return base64.RawStdEncoding.EncodeToString([]byte(applicationID.String())) + ".fake.token"
+}
+
+// Request is a REST request sent by the bot.
+type Request struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is a canned REST response.
+type Response struct {
+	Status int
+	Body   any
+}
+
+// Transport is an http.RoundTripper which records requests and answers them with canned responses.
+// Requests without a canned response are answered with 200 and an empty JSON object.
+type Transport struct {
+	mu        sync.Mutex
+	requests  []Request
+	responses map[string]Response
+}
+
+// Respond registers a canned response for requests matching method and path, for example "GET", "/applications/@me".
+func (t *Transport) Respond(method string, path string, response Response) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.responses[method+" "+path] = response
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	path := strings.TrimPrefix(r.URL.Path, fmt.Sprintf("/api/v%d", rest.Version))
+	request := Request{Method: r.Method, Path: path}
+	if r.Body != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		request.Body = normalizeBody(r.Header.Get("Content-Type"), body)
+	}
+
+	t.mu.Lock()
+	t.requests = append(t.requests, request)
+	response, ok := t.responses[r.Method+" "+path]
+	t.mu.Unlock()
+	if !ok {
+		response = Response{Status: http.StatusOK, Body: struct{}{}}
+	}
+
+	var body []byte
+	if response.Body != nil {
+		var err error
+		if body, err = json.Marshal(response.Body); err != nil {
+			return nil, err
+		}
+	}
+	return &http.Response{
+		StatusCode: response.Status,
+		Status:     http.StatusText(response.Status),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+// Requests returns the requests recorded so far.
+func (t *Transport) Requests() []Request {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	requests := make([]Request, len(t.requests))
+	copy(requests, t.requests)
+	return requests
+}
+
+// normalizeBody re-encodes JSON bodies with sorted keys so golden files are stable. Multipart bodies, used to upload
+// files, are recorded as an object of their parts without the random boundary. Other bodies are kept as a JSON string.
+func normalizeBody(contentType string, body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		if parts, err := multipartParts(body, params["boundary"]); err == nil {
+			return marshal(parts, "")
+		}
+	}
+	return marshal(jsonValue(body), "")
+}
+
+// multipartParts returns the parts of a multipart body keyed by form name. JSON parts are decoded, files are
+// recorded with their file name and content.
+func multipartParts(body []byte, boundary string) (map[string]any, error) {
+	parts := make(map[string]any)
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		if part.FileName() != "" {
+			parts[part.FormName()] = map[string]string{"filename": part.FileName(), "content": string(content)}
+			continue
+		}
+		parts[part.FormName()] = jsonValue(content)
+	}
+}
+
+// jsonValue decodes body as JSON, or returns it as a string if it is not valid JSON.
+func jsonValue(body []byte) any {
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return string(body)
+	}
+	return v
+}
+
+// marshal encodes v without escaping HTML characters, which Discord messages use for links.
+func marshal(v any, indent string) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+	_ = encoder.Encode(v)
+	return buf.Bytes()
+}
+
+// Harness wires a disgo client to a fake gateway and REST transport.
+type Harness struct {
+	tb        testing.TB
+	Transport *Transport
+	Gateway   *Gateway
+	client    bot.Client
+	sequence  int
+}
+
+// New creates a harness. Pass ConfigOpts to the disgo client and then Attach the client.
+func New(tb testing.TB) *Harness {
+	return &Harness{
+		tb:        tb,
+		Transport: &Transport{responses: make(map[string]Response)},
+		Gateway:   &Gateway{status: gateway.StatusReady},
+	}
+}
+
+// ConfigOpts returns the disgo options replacing the gateway and REST transport. They must be applied after any other gateway options.
+func (h *Harness) ConfigOpts() []bot.ConfigOpt {
+	return []bot.ConfigOpt{
+		bot.WithGateway(h.Gateway),
+		bot.WithRestClientConfigOpts(rest.WithHTTPClient(&http.Client{Transport: h.Transport})),
+	}
+}
+
+// Attach sets the client events are dispatched to.
+func (h *Harness) Attach(client bot.Client) {
+	h.client = client
+}
+
+// Dispatch injects a gateway event. data is marshalled to JSON and decoded the same way disgo decodes events from Discord.
+func (h *Harness) Dispatch(eventType gateway.EventType, data any) {
+	h.tb.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		h.tb.Fatalf("discordtest: error marshalling %s event: %v", eventType, err)
+	}
+	h.dispatchRaw(eventType, raw)
+}
+
+func (h *Harness) dispatchRaw(eventType gateway.EventType, raw []byte) {
+	h.tb.Helper()
+	if h.client == nil {
+		h.tb.Fatalf("discordtest: no client attached")
+	}
+	eventData, err := gateway.UnmarshalEventData(raw, eventType)
+	if err != nil {
+		h.tb.Fatalf("discordtest: error decoding %s event: %v", eventType, err)
+	}
+	h.sequence++
+	h.client.EventManager().HandleGatewayEvent(eventType, h.sequence, 0, eventData)
+}
+
+// Replay dispatches every event recorded in the file at path. The file holds one gateway payload per line,
+// for example {"op":0,"t":"VOICE_STATE_UPDATE","d":{...}}. Blank lines and non dispatch payloads are skipped.
+func (h *Harness) Replay(path string) {
+	h.tb.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		h.tb.Fatalf("discordtest: error opening fixture: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		var message struct {
+			Op gateway.Opcode    `json:"op"`
+			T  gateway.EventType `json:"t"`
+			D  json.RawMessage   `json:"d"`
+		}
+		if err = json.Unmarshal(data, &message); err != nil {
+			h.tb.Fatalf("discordtest: %s:%d: %v", path, line, err)
+		}
+		if message.Op != gateway.OpcodeDispatch {
+			continue
+		}
+		h.dispatchRaw(message.T, message.D)
+	}
+	if err = scanner.Err(); err != nil {
+		h.tb.Fatalf("discordtest: error reading fixture: %v", err)
+	}
+}
+
+// Recording is everything the bot sent to Discord.
+type Recording struct {
+	Rest    []Request     `json:"rest"`
+	Gateway []GatewaySend `json:"gateway"`
+}
+
+// Recording returns the REST requests and gateway payloads sent so far.
+func (h *Harness) Recording() Recording {
+	return Recording{Rest: h.Transport.Requests(), Gateway: h.Gateway.Sent()}
+}
+
+// AssertGolden compares the recording against testdata/golden/<name>.json. Run the tests with -update to rewrite the file.
+func (h *Harness) AssertGolden(name string) {
+	h.tb.Helper()
+	actual := marshal(h.Recording(), "  ")
+
+	path := filepath.Join("testdata", "golden", name+".json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			h.tb.Fatalf("discordtest: error creating golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o644); err != nil {
+			h.tb.Fatalf("discordtest: error writing golden file: %v", err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		h.tb.Fatalf("discordtest: error reading golden file (run with -update to create it): %v", err)
+	}
+	if !bytes.Equal(expected, actual) {
+		h.tb.Errorf("discordtest: recording does not match %s\n--- expected\n%s\n--- actual\n%s", path, expected, actual)
+	}
+}
+
+// GatewaySend is a payload the bot sent over the gateway.
+type GatewaySend struct {
+	Op   gateway.Opcode      `json:"op"`
+	Data gateway.MessageData `json:"d"`
+}
+
+// Gateway is a gateway.Gateway which never connects and records every payload sent.
+type Gateway struct {
+	mu     sync.Mutex
+	sent   []GatewaySend
+	status gateway.Status
+}
+
+var _ gateway.Gateway = (*Gateway)(nil)
+
+// Sent returns the payloads sent so far.
+func (g *Gateway) Sent() []GatewaySend {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sent := make([]GatewaySend, len(g.sent))
+	copy(sent, g.sent)
+	return sent
+}
+
+// SetStatus changes the status reported by the gateway.
+func (g *Gateway) SetStatus(status gateway.Status) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = status
+}
+
+func (g *Gateway) ShardID() int                                 { return 0 }
+func (g *Gateway) ShardCount() int                              { return 1 }
+func (g *Gateway) SessionID() *string                           { return nil }
+func (g *Gateway) LastSequenceReceived() *int                   { return nil }
+func (g *Gateway) Intents() gateway.Intents                     { return gateway.IntentsNone }
+func (g *Gateway) Open(_ context.Context) error                 { return nil }
+func (g *Gateway) Close(_ context.Context)                      {}
+func (g *Gateway) Latency() time.Duration                       { return 0 }
+func (g *Gateway) Presence() *gateway.MessageDataPresenceUpdate { return nil }
+
+func (g *Gateway) CloseWithCode(_ context.Context, _ int, _ string) {}
+
+func (g *Gateway) Status() gateway.Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status
+}
+
+func (g *Gateway) Send(_ context.Context, op gateway.Opcode, data gateway.MessageData) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.status != gateway.StatusReady {
+		return fmt.Errorf("gateway is not ready: %s", g.status)
+	}
+	g.sent = append(g.sent, GatewaySend{Op: op, Data: data})
+	return nil
+}
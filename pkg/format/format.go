@@ -0,0 +1,86 @@
+// Package format renders track positions, lengths and progress bars for chat messages.
+package format
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+)
+
+const (
+	// Live is shown instead of the length of streams, whose reported length is meaningless.
+	Live = "LIVE"
+	// Unknown is shown for tracks whose length Lavalink didn't report.
+	Unknown = "-:--"
+	// BarWidth is the number of segments in the progress bars used in messages.
+	BarWidth = 16
+)
+
+// Duration formats d as m:ss, or as h:mm:ss from one hour on. Negative durations are shown as 0:00.
+func Duration(d lavalink.Duration) string {
+	d = max(d, 0)
+	if d >= lavalink.Hour {
+		return fmt.Sprintf("%d:%02d:%02d", d.Hours(), d.MinutesPart(), d.SecondsPart())
+	}
+	return fmt.Sprintf("%d:%02d", d.Minutes(), d.SecondsPart())
+}
+
+// Length formats the length of a track.
+func Length(info lavalink.TrackInfo) string {
+	switch {
+	case info.IsStream:
+		return Live
+	case info.Length <= 0:
+		return Unknown
+	default:
+		return Duration(info.Length)
+	}
+}
+
+// Link formats the title of a track as a link to it. Tracks without a URI, such as some local or HTTP sources, are
+// shown by title only.
+func Link(info lavalink.TrackInfo) string {
+	if info.URI == nil || *info.URI == "" {
+		return fmt.Sprintf("`%s`", info.Title)
+	}
+	return fmt.Sprintf("[`%s`](<%s>)", info.Title, *info.URI)
+}
+
+// Total formats the combined length of tracks. Streams and tracks without a length can't be counted, which is
+// marked with a trailing +.
+func Total(tracks []lavalink.Track) string {
+	var total lavalink.Duration
+	uncounted := false
+	for _, track := range tracks {
+		if track.Info.IsStream || track.Info.Length <= 0 {
+			uncounted = true
+			continue
+		}
+		total += track.Info.Length
+	}
+	if uncounted {
+		return Duration(total) + "+"
+	}
+	return Duration(total)
+}
+
+// Bar renders position within length as width segments with a marker at the current position, e.g. ━━━●────.
+// The marker stays at the start when length is unknown.
+func Bar(position lavalink.Duration, length lavalink.Duration, width int) string {
+	width = max(width, 1)
+	marker := 0
+	if length > 0 {
+		marker = int(int64(min(max(position, 0), length)) * int64(width-1) / int64(length))
+	}
+	return strings.Repeat("━", marker) + "●" + strings.Repeat("─", width-1-marker)
+}
+
+// Progress renders the position within a track followed by a progress bar and its length, e.g.
+// `1:23` ━━━●──── `3:45`. Streams have no bar as they have no end.
+func Progress(position lavalink.Duration, info lavalink.TrackInfo) string {
+	if info.IsStream {
+		return fmt.Sprintf("`%s` 🔴 %s", Duration(position), Live)
+	}
+	return fmt.Sprintf("`%s` %s `%s`", Duration(position), Bar(position, info.Length, BarWidth), Length(info))
+}
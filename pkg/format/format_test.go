@@ -0,0 +1,55 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Duration(t *testing.T) {
+	assert.Equal(t, "0:00", Duration(0))
+	assert.Equal(t, "0:00", Duration(-lavalink.Second))
+	assert.Equal(t, "0:05", Duration(5*lavalink.Second+999))
+	assert.Equal(t, "59:59", Duration(lavalink.Hour-lavalink.Second))
+	assert.Equal(t, "1:00:00", Duration(lavalink.Hour))
+	assert.Equal(t, "2:03:04", Duration(2*lavalink.Hour+3*lavalink.Minute+4*lavalink.Second))
+	assert.Equal(t, "26:00:00", Duration(26*lavalink.Hour))
+}
+
+func Test_Length(t *testing.T) {
+	assert.Equal(t, "3:45", Length(lavalink.TrackInfo{Length: 3*lavalink.Minute + 45*lavalink.Second}))
+	assert.Equal(t, Live, Length(lavalink.TrackInfo{Length: 9223372036854775807, IsStream: true}))
+	assert.Equal(t, Unknown, Length(lavalink.TrackInfo{}))
+}
+
+func Test_Link(t *testing.T) {
+	uri := "https://example.com/a"
+	assert.Equal(t, "[`A`](<https://example.com/a>)", Link(lavalink.TrackInfo{Title: "A", URI: &uri}))
+	assert.Equal(t, "`A`", Link(lavalink.TrackInfo{Title: "A"}))
+}
+
+func Test_Total(t *testing.T) {
+	tracks := []lavalink.Track{
+		{Info: lavalink.TrackInfo{Length: lavalink.Hour}},
+		{Info: lavalink.TrackInfo{Length: 30 * lavalink.Minute}},
+	}
+	assert.Equal(t, "1:30:00", Total(tracks))
+	assert.Equal(t, "1:30:00+", Total(append(tracks, lavalink.Track{Info: lavalink.TrackInfo{IsStream: true}})))
+	assert.Equal(t, "0:00", Total(nil))
+}
+
+func Test_Bar(t *testing.T) {
+	assert.Equal(t, "●────", Bar(0, lavalink.Minute, 5))
+	assert.Equal(t, "━━●──", Bar(30*lavalink.Second, lavalink.Minute, 5))
+	assert.Equal(t, "━━━━●", Bar(lavalink.Minute, lavalink.Minute, 5))
+	assert.Equal(t, "━━━━●", Bar(2*lavalink.Minute, lavalink.Minute, 5), "positions past the end are clamped")
+	assert.Equal(t, "●────", Bar(30*lavalink.Second, 0, 5), "unknown lengths keep the marker at the start")
+	assert.Equal(t, "●", Bar(0, lavalink.Minute, 0))
+}
+
+func Test_Progress(t *testing.T) {
+	assert.Equal(t, "`1:00` ━━━━━━━●──────── `2:00`", Progress(lavalink.Minute, lavalink.TrackInfo{Length: 2 * lavalink.Minute}))
+	assert.Equal(t, "`1:00` 🔴 LIVE", Progress(lavalink.Minute, lavalink.TrackInfo{Length: 1, IsStream: true}))
+	assert.Equal(t, "`1:00` ●─────────────── `-:--`", Progress(lavalink.Minute, lavalink.TrackInfo{}))
+}
@@ -0,0 +1,139 @@
+package lavalinktest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/gorilla/websocket"
+)
+
+// disgolink closes the WebSocket of a node before forgetting it, without holding the lock its listen goroutine takes
+// when a read fails. When the listen goroutine wakes up in between it closes the node a second time, racing with
+// Client.Close. Connections to a Server are therefore dialed through clientConn, which holds that read until
+// Server.CloseClient returned from Client.Close.
+
+var (
+	dialerOnce sync.Once
+	serversMu  sync.Mutex
+	servers    = make(map[string]*Server)
+)
+
+// registerServer makes the WebSocket dialer used by disgolink wrap connections to s.
+func registerServer(s *Server) {
+	dialerOnce.Do(func() {
+		websocket.DefaultDialer.NetDialContext = dialClient
+	})
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	servers[s.server.Listener.Addr().String()] = s
+}
+
+func lookupServer(addr string) *Server {
+	serversMu.Lock()
+	defer serversMu.Unlock()
+	return servers[addr]
+}
+
+func dialClient(ctx context.Context, network string, addr string) (net.Conn, error) {
+	s := lookupServer(addr)
+	if s != nil && s.isClosed() {
+		// disgolink keeps reconnecting to a node that is gone. The attempt is held until the client is closed,
+		// so reconnecting doesn't race with closing it.
+		select {
+		case s.redials <- struct{}{}:
+		default:
+		}
+		select {
+		case <-s.released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("lavalinktest: server %s is closed", addr)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil || s == nil {
+		return conn, err
+	}
+	client := &clientConn{Conn: conn, released: s.released, done: make(chan struct{})}
+	s.mu.Lock()
+	s.clients = append(s.clients, client)
+	s.mu.Unlock()
+	return client, nil
+}
+
+// clientConn is the client side of a WebSocket connection to a Server.
+type clientConn struct {
+	net.Conn
+	released <-chan struct{}
+	// closed is set once the client closes the connection, dropped when reading failed before.
+	closed  atomic.Bool
+	dropped atomic.Bool
+	// done is closed once a read failed and the listen goroutine lets go of the connection.
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+func (c *clientConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+func (c *clientConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if err != nil {
+		if c.closed.Load() {
+			<-c.released
+		} else {
+			c.dropped.Store(true)
+		}
+		c.doneOnce.Do(func() { close(c.done) })
+	}
+	return n, err
+}
+
+// CloseClient closes client and waits until it let go of its connections to the server. Call it before Close,
+// closing the server first makes disgolink reconnect.
+func (s *Server) CloseClient(client disgolink.Client) {
+	s.mu.Lock()
+	clients := s.clients
+	closed := s.closed
+	s.mu.Unlock()
+	timeout := time.After(5 * time.Second)
+
+	// After a node failure disgolink closes the node on its own before reconnecting, which has to happen before the
+	// client is closed. Only the last connection can be open, earlier ones were replaced.
+	if closed && len(clients) > 0 {
+		last := clients[len(clients)-1]
+		if !s.await(last.done, timeout) {
+			return
+		}
+		if last.dropped.Load() && !s.await(s.redials, timeout) {
+			return
+		}
+	}
+
+	client.Close()
+	s.releaseOnce.Do(func() { close(s.released) })
+	for _, conn := range clients {
+		if !s.await(conn.done, timeout) {
+			return
+		}
+	}
+}
+
+func (s *Server) await(ch <-chan struct{}, timeout <-chan time.Time) bool {
+	select {
+	case <-ch:
+		return true
+	case <-timeout:
+		s.tb.Errorf("lavalinktest: timed out waiting for the client to let go of its connection")
+		return false
+	}
+}
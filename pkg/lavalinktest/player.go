@@ -0,0 +1,41 @@
+package lavalinktest
+
+import (
+	"context"
+
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+)
+
+// Player wraps a player of a client connected to a Server, so pausing and resuming it can be tested with the race
+// detector. disgolink records the pause state of a player a second time from a goroutine of its own after updating
+// it, which is reported as soon as the state is read again. The wrapped player sends pauses to the node itself and
+// records them before Update returns, every other update goes through the player.
+func Player(player disgolink.Player) disgolink.Player {
+	return pausingPlayer{Player: player}
+}
+
+type pausingPlayer struct {
+	disgolink.Player
+}
+
+func (p pausingPlayer) Update(ctx context.Context, opts ...lavalink.PlayerUpdateOpt) error {
+	update := lavalink.DefaultPlayerUpdate()
+	update.Apply(opts)
+	if update.Paused == nil || *update != (lavalink.PlayerUpdate{Paused: update.Paused}) {
+		return p.Player.Update(ctx, opts...)
+	}
+	node := p.Node()
+	if node == nil {
+		return disgolink.ErrPlayerNoNode
+	}
+	if _, err := node.Rest().UpdatePlayer(ctx, node.SessionID(), p.GuildID(), *update); err != nil {
+		return err
+	}
+	var event lavalink.Event = lavalink.PlayerResumeEvent{GuildID_: p.GuildID()}
+	if *update.Paused {
+		event = lavalink.PlayerPauseEvent{GuildID_: p.GuildID()}
+	}
+	p.OnEvent(event)
+	return nil
+}
@@ -0,0 +1,569 @@
+// Package lavalinktest provides an in-process fake Lavalink v4 server for tests.
+//
+// The server implements the REST endpoints used by disgolink (info, version, stats, loadtracks,
+// decodetrack(s), sessions, players and the LavaLyrics plugin) and the WebSocket used for events. Player updates which
+// start or stop a track emit the matching TrackStartEvent/TrackEndEvent, and tests can finish
+// tracks or simulate node failures to drive the bot through its event listeners. Clients are closed with
+// Server.CloseClient before the server, and players are paused through Player.
+package lavalinktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/gorilla/websocket"
+)
+
+// Password is the password the fake server expects from clients.
+const Password = "youshallnotpass"
+
+// Version is the version reported by the fake server.
+const Version = "4.0.0"
+
+var upgrader = websocket.Upgrader{}
+
+// Server is a fake Lavalink v4 node.
+type Server struct {
+	tb     testing.TB
+	server *httptest.Server
+
+	mu          sync.Mutex
+	info        lavalink.Info
+	loadResults map[string]lavalink.LoadResult
+	tracks      map[string]lavalink.Track
+	players     map[snowflake.ID]*lavalink.Player
+	updates     []PlayerUpdate
+	lyrics      map[string]any
+	nodeStats   lavalink.Stats
+	conn        *websocket.Conn
+	connMu      sync.Mutex
+	sessions    int
+	sessionID   string
+	closed      bool
+
+	// clients are the connections dialed to the server, released is closed by CloseClient. redials receives the
+	// reconnection attempts once the server is closed.
+	clients     []*clientConn
+	released    chan struct{}
+	redials     chan struct{}
+	releaseOnce sync.Once
+}
+
+// PlayerUpdate is a player update received by the server.
+type PlayerUpdate struct {
+	GuildID snowflake.ID
+	Update  lavalink.PlayerUpdate
+}
+
+// NewServer starts a fake Lavalink server which is closed when the test finishes.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	s := &Server{
+		tb: tb,
+		info: lavalink.Info{
+			Version:        lavalink.Version{Semver: Version, Major: 4},
+			SourceManagers: []string{"youtube", "soundcloud", "http"},
+			Filters:        []string{"equalizer", "timescale", "volume"},
+		},
+		loadResults: make(map[string]lavalink.LoadResult),
+		tracks:      make(map[string]lavalink.Track),
+		players:     make(map[snowflake.ID]*lavalink.Player),
+		released:    make(chan struct{}),
+		redials:     make(chan struct{}, 16),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /version", s.handleVersion)
+	mux.HandleFunc("GET /v4/info", s.handleInfo)
+	mux.HandleFunc("GET /v4/stats", s.handleStats)
+	mux.HandleFunc("GET /v4/websocket", s.handleWebSocket)
+	mux.HandleFunc("GET /v4/loadtracks", s.handleLoadTracks)
+	mux.HandleFunc("GET /v4/lyrics", s.handleLyrics)
+	mux.HandleFunc("GET /v4/decodetrack", s.handleDecodeTrack)
+	mux.HandleFunc("POST /v4/decodetracks", s.handleDecodeTracks)
+	mux.HandleFunc("PATCH /v4/sessions/{sessionID}", s.handleUpdateSession)
+	mux.HandleFunc("GET /v4/sessions/{sessionID}/players", s.handleGetPlayers)
+	mux.HandleFunc("GET /v4/sessions/{sessionID}/players/{guildID}", s.handleGetPlayer)
+	mux.HandleFunc("PATCH /v4/sessions/{sessionID}/players/{guildID}", s.handleUpdatePlayer)
+	mux.HandleFunc("DELETE /v4/sessions/{sessionID}/players/{guildID}", s.handleDestroyPlayer)
+
+	s.server = httptest.NewServer(s.authenticate(mux))
+	registerServer(s)
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// NodeConfig returns a disgolink node configuration pointing at the server.
+func (s *Server) NodeConfig(name string) disgolink.NodeConfig {
+	return disgolink.NodeConfig{
+		Name:     name,
+		Address:  strings.TrimPrefix(s.server.URL, "http://"),
+		Password: Password,
+	}
+}
+
+// URL returns the base HTTP URL of the server.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// SetInfo replaces the info returned by /v4/info.
+func (s *Server) SetInfo(info lavalink.Info) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.info = info
+}
+
+// AddTrack makes identifier resolve to track and registers the track for decoding and playback.
+func (s *Server) AddTrack(identifier string, track lavalink.Track) {
+	s.SetLoadResult(identifier, lavalink.LoadResult{LoadType: lavalink.LoadTypeTrack, Data: track})
+}
+
+// SetLoadResult makes identifier resolve to result. Tracks in the result are registered for decoding and playback.
+func (s *Server) SetLoadResult(identifier string, result lavalink.LoadResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch data := result.Data.(type) {
+	case lavalink.Track:
+		result.LoadType = lavalink.LoadTypeTrack
+		s.tracks[data.Encoded] = data
+	case lavalink.Playlist:
+		result.LoadType = lavalink.LoadTypePlaylist
+		for _, track := range data.Tracks {
+			s.tracks[track.Encoded] = track
+		}
+	case lavalink.Search:
+		result.LoadType = lavalink.LoadTypeSearch
+		for _, track := range data {
+			s.tracks[track.Encoded] = track
+		}
+	case lavalink.Exception:
+		result.LoadType = lavalink.LoadTypeError
+	default:
+		result.LoadType = lavalink.LoadTypeEmpty
+		result.Data = lavalink.Empty{}
+	}
+	s.loadResults[identifier] = result
+}
+
+// SetLyrics installs the LavaLyrics plugin and makes it return lyrics for the encoded track. Before the first call
+// the plugin is not installed and lyrics requests fail with 404, tracks without lyrics are answered with 204.
+func (s *Server) SetLyrics(encoded string, lyrics any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lyrics == nil {
+		s.lyrics = make(map[string]any)
+		s.info.Plugins = append(s.info.Plugins, lavalink.Plugin{Name: "lavalyrics-plugin", Version: "1.0.0"})
+	}
+	s.lyrics[encoded] = lyrics
+}
+
+// Player returns a copy of the server side state of the guild's player.
+func (s *Server) Player(guildID snowflake.ID) (lavalink.Player, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	player, ok := s.players[guildID]
+	if !ok {
+		return lavalink.Player{}, false
+	}
+	return *player, true
+}
+
+// CurrentTrack returns the encoded track the guild's player is playing, or an empty string.
+func (s *Server) CurrentTrack(guildID snowflake.ID) string {
+	player, ok := s.Player(guildID)
+	if !ok || player.Track == nil {
+		return ""
+	}
+	return player.Track.Encoded
+}
+
+// Updates returns all player updates received so far.
+func (s *Server) Updates() []PlayerUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	updates := make([]PlayerUpdate, len(s.updates))
+	copy(updates, s.updates)
+	return updates
+}
+
+// Connected reports whether a client WebSocket is connected.
+func (s *Server) Connected() bool {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	return s.conn != nil
+}
+
+// FinishTrack ends the guild's current track with reason, emitting a TrackEndEvent.
+func (s *Server) FinishTrack(guildID snowflake.ID, reason lavalink.TrackEndReason) error {
+	s.mu.Lock()
+	player, ok := s.players[guildID]
+	if !ok || player.Track == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("guild %s has no playing track", guildID)
+	}
+	track := *player.Track
+	player.Track = nil
+	s.mu.Unlock()
+
+	return s.Emit(lavalink.TrackEndEvent{Track: track, Reason: reason, GuildID_: guildID})
+}
+
+// SetStats sets the uptime, memory, CPU and frame statistics reported by the server and sends them to the connected
+// client. The player counts are always derived from the server's players.
+func (s *Server) SetStats(stats lavalink.Stats) error {
+	s.mu.Lock()
+	s.nodeStats = stats
+	s.mu.Unlock()
+	return s.Emit(lavalink.StatsMessage(s.stats()))
+}
+
+// Emit sends a message, such as an event, to the connected client.
+func (s *Server) Emit(message lavalink.Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	// Events and stats are discriminated by op (and type), which the lavalink types only expose as methods.
+	var raw map[string]any
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	raw["op"] = message.Op()
+	if event, ok := message.(lavalink.Event); ok {
+		raw["type"] = event.Type()
+	}
+	return s.writeJSON(raw)
+}
+
+// DropConnection closes the client WebSocket, simulating a network failure. disgolink will try to reconnect.
+func (s *Server) DropConnection() {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// Close shuts the server down, simulating a node failure.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.DropConnection()
+	s.server.CloseClientConnections()
+	s.server.Close()
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Server) writeJSON(v any) error {
+	s.connMu.Lock()
+	defer s.connMu.Unlock()
+	if s.conn == nil {
+		return fmt.Errorf("no client connected")
+	}
+	_ = s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return s.conn.WriteJSON(v)
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != Password {
+			writeError(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte(Version))
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	info := s.info
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.stats())
+}
+
+func (s *Server) stats() lavalink.Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.nodeStats
+	stats.Players = len(s.players)
+	stats.PlayingPlayers = 0
+	if stats.CPU.Cores == 0 {
+		stats.CPU.Cores = 1
+	}
+	for _, player := range s.players {
+		if player.Track != nil && !player.Paused {
+			stats.PlayingPlayers++
+		}
+	}
+	return stats
+}
+
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.tb.Logf("lavalinktest: error upgrading websocket: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.sessions++
+	s.sessionID = fmt.Sprintf("session-%d", s.sessions)
+	sessionID := s.sessionID
+	s.mu.Unlock()
+
+	s.connMu.Lock()
+	if s.conn != nil {
+		_ = s.conn.Close()
+	}
+	s.conn = conn
+	s.connMu.Unlock()
+
+	if err = s.writeJSON(map[string]any{"op": lavalink.OpReady, "resumed": false, "sessionId": sessionID}); err != nil {
+		s.tb.Logf("lavalinktest: error sending ready message: %v", err)
+		return
+	}
+	if err = s.Emit(lavalink.StatsMessage(s.stats())); err != nil {
+		s.tb.Logf("lavalinktest: error sending stats message: %v", err)
+	}
+
+	// Drain incoming messages so close frames are processed.
+	go func() {
+		for {
+			if _, _, readErr := conn.ReadMessage(); readErr != nil {
+				return
+			}
+		}
+	}()
+}
+
+func (s *Server) handleLoadTracks(w http.ResponseWriter, r *http.Request) {
+	identifier := r.URL.Query().Get("identifier")
+	s.mu.Lock()
+	result, ok := s.loadResults[identifier]
+	s.mu.Unlock()
+	if !ok {
+		result = lavalink.LoadResult{LoadType: lavalink.LoadTypeEmpty, Data: lavalink.Empty{}}
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) handleLyrics(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	installed := s.lyrics != nil
+	lyrics, ok := s.lyrics[r.URL.Query().Get("track")]
+	s.mu.Unlock()
+	switch {
+	case !installed:
+		writeError(w, r, http.StatusNotFound, "Not Found")
+	case !ok:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusOK, lyrics)
+	}
+}
+
+func (s *Server) handleDecodeTrack(w http.ResponseWriter, r *http.Request) {
+	track, ok := s.track(r.URL.Query().Get("track"))
+	if !ok {
+		writeError(w, r, http.StatusBadRequest, "unknown track")
+		return
+	}
+	writeJSON(w, http.StatusOK, track)
+}
+
+func (s *Server) handleDecodeTracks(w http.ResponseWriter, r *http.Request) {
+	var encoded []string
+	if err := json.NewDecoder(r.Body).Decode(&encoded); err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	tracks := make([]lavalink.Track, 0, len(encoded))
+	for _, e := range encoded {
+		track, ok := s.track(e)
+		if !ok {
+			writeError(w, r, http.StatusBadRequest, "unknown track")
+			return
+		}
+		tracks = append(tracks, track)
+	}
+	writeJSON(w, http.StatusOK, tracks)
+}
+
+func (s *Server) track(encoded string) (lavalink.Track, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	track, ok := s.tracks[encoded]
+	return track, ok
+}
+
+func (s *Server) handleUpdateSession(w http.ResponseWriter, r *http.Request) {
+	var update lavalink.SessionUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	session := lavalink.Session{}
+	if update.Resuming != nil {
+		session.Resuming = *update.Resuming
+	}
+	if update.Timeout != nil {
+		session.Timeout = *update.Timeout
+	}
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (s *Server) handleGetPlayers(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	players := make([]lavalink.Player, 0, len(s.players))
+	for _, player := range s.players {
+		players = append(players, *player)
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, players)
+}
+
+func (s *Server) handleGetPlayer(w http.ResponseWriter, r *http.Request) {
+	guildID, err := snowflake.Parse(r.PathValue("guildID"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	player, ok := s.Player(guildID)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, "Player not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, player)
+}
+
+func (s *Server) handleUpdatePlayer(w http.ResponseWriter, r *http.Request) {
+	guildID, err := snowflake.Parse(r.PathValue("guildID"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	var update lavalink.PlayerUpdate
+	if err = json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	noReplace := r.URL.Query().Get("noReplace") == "true"
+
+	s.mu.Lock()
+	s.updates = append(s.updates, PlayerUpdate{GuildID: guildID, Update: update})
+	player, ok := s.players[guildID]
+	if !ok {
+		player = &lavalink.Player{GuildID: guildID, Volume: 100}
+		s.players[guildID] = player
+	}
+
+	var events []lavalink.Message
+	if update.Track != nil && update.Track.Encoded != nil && !(noReplace && player.Track != nil) {
+		previous := player.Track
+		if update.Track.Encoded.IsNull() {
+			player.Track = nil
+			if previous != nil {
+				events = append(events, lavalink.TrackEndEvent{Track: *previous, Reason: lavalink.TrackEndReasonStopped, GuildID_: guildID})
+			}
+		} else {
+			encoded := update.Track.Encoded.Value()
+			track, known := s.tracks[encoded]
+			if !known {
+				s.mu.Unlock()
+				writeError(w, r, http.StatusBadRequest, "unknown track")
+				return
+			}
+			player.Track = &track
+			player.State = lavalink.PlayerState{Time: lavalink.Timestamp{Time: time.Now()}, Connected: true}
+			if previous != nil {
+				events = append(events, lavalink.TrackEndEvent{Track: *previous, Reason: lavalink.TrackEndReasonReplaced, GuildID_: guildID})
+			}
+			// The state is sent before the start event so the client knows the position once the track has started.
+			events = append(events,
+				lavalink.PlayerUpdateMessage{State: player.State, GuildID: guildID},
+				lavalink.TrackStartEvent{Track: track, GuildID_: guildID},
+			)
+		}
+	}
+	if update.Position != nil {
+		player.State.Position = *update.Position
+	}
+	if update.Volume != nil {
+		player.Volume = *update.Volume
+	}
+	if update.Paused != nil {
+		player.Paused = *update.Paused
+	}
+	if update.Filters != nil {
+		player.Filters = *update.Filters
+	}
+	if update.Voice != nil {
+		player.Voice = *update.Voice
+	}
+	response := *player
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, response)
+
+	// Events are sent after the response, like a real node which starts playback asynchronously.
+	go func() {
+		for _, event := range events {
+			if emitErr := s.Emit(event); emitErr != nil {
+				s.tb.Logf("lavalinktest: error emitting %T: %v", event, emitErr)
+			}
+		}
+	}()
+}
+
+func (s *Server) handleDestroyPlayer(w http.ResponseWriter, r *http.Request) {
+	guildID, err := snowflake.Parse(r.PathValue("guildID"))
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.mu.Lock()
+	delete(s.players, guildID)
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeJSON(w, status, lavalink.Error{
+		Timestamp:   lavalink.Timestamp{Time: time.Now()},
+		Status:      status,
+		StatusError: http.StatusText(status),
+		Message:     message,
+		Path:        r.URL.Path,
+	})
+}
@@ -0,0 +1,84 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgolink/v3/disgolink"
+	"github.com/disgoorg/disgolink/v3/lavalink"
+)
+
+// LavaLyrics fetches lyrics through the LavaLyrics plugin of a Lavalink node.
+type LavaLyrics struct {
+	// Node returns the node to ask, or nil when no node is available.
+	Node func() disgolink.Node
+}
+
+type lavaLyricsResponse struct {
+	SourceName string `json:"sourceName"`
+	Provider   string `json:"provider"`
+	Text       string `json:"text"`
+	Lines      []struct {
+		Timestamp int64  `json:"timestamp"`
+		Line      string `json:"line"`
+	} `json:"lines"`
+}
+
+func (LavaLyrics) Name() string {
+	return "LavaLyrics"
+}
+
+// Lyrics implements Provider. It returns ErrUnsupported when the node does not have the plugin installed.
+func (l LavaLyrics) Lyrics(ctx context.Context, track lavalink.Track) (*Lyrics, error) {
+	node := l.Node()
+	if node == nil {
+		return nil, ErrUnsupported
+	}
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, "/v4/lyrics?skipTrackSource=false&track="+url.QueryEscape(track.Encoded), nil)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := node.Rest().Do(rq)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting lyrics: %w", err)
+	}
+	defer rs.Body.Close()
+
+	switch rs.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return nil, ErrNotFound
+	case http.StatusNotFound:
+		return nil, ErrUnsupported
+	default:
+		body, _ := io.ReadAll(io.LimitReader(rs.Body, 1024))
+		return nil, fmt.Errorf("error requesting lyrics: %s: %s", rs.Status, body)
+	}
+
+	var response lavaLyricsResponse
+	if err = json.NewDecoder(rs.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("error decoding lyrics: %w", err)
+	}
+	lyrics := &Lyrics{Provider: response.Provider, Text: response.Text}
+	if lyrics.Provider == "" {
+		lyrics.Provider = response.SourceName
+	}
+	texts := make([]string, 0, len(response.Lines))
+	for _, line := range response.Lines {
+		lyrics.Lines = append(lyrics.Lines, Line{Timestamp: time.Duration(line.Timestamp) * time.Millisecond, Text: line.Line})
+		texts = append(texts, line.Line)
+	}
+	if lyrics.Text == "" {
+		lyrics.Text = strings.Join(texts, "\n")
+	}
+	if strings.TrimSpace(lyrics.Text) == "" {
+		return nil, ErrNotFound
+	}
+	return lyrics, nil
+}
@@ -0,0 +1,78 @@
+package lyrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+)
+
+// LRCLIB fetches lyrics from an LRCLIB compatible API, see https://lrclib.net/docs.
+type LRCLIB struct {
+	// BaseURL is the URL of the API without the /api suffix, e.g. https://lrclib.net.
+	BaseURL string
+	Client  *http.Client
+}
+
+type lrclibResult struct {
+	Instrumental bool   `json:"instrumental"`
+	PlainLyrics  string `json:"plainLyrics"`
+	SyncedLyrics string `json:"syncedLyrics"`
+}
+
+func (LRCLIB) Name() string {
+	return "LRCLIB"
+}
+
+// Lyrics implements Provider by searching for the track's title and author.
+func (l LRCLIB) Lyrics(ctx context.Context, track lavalink.Track) (*Lyrics, error) {
+	query := url.Values{}
+	query.Set("track_name", track.Info.Title)
+	if track.Info.Author != "" {
+		query.Set("artist_name", track.Info.Author)
+	}
+	rq, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(l.BaseURL, "/")+"/api/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := l.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	rs, err := client.Do(rq)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting lyrics: %w", err)
+	}
+	defer rs.Body.Close()
+	if rs.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error requesting lyrics: %s", rs.Status)
+	}
+
+	var results []lrclibResult
+	if err = json.NewDecoder(rs.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("error decoding lyrics: %w", err)
+	}
+	for _, result := range results {
+		if result.Instrumental || (result.PlainLyrics == "" && result.SyncedLyrics == "") {
+			continue
+		}
+		lyrics := &Lyrics{Provider: l.Name(), Text: result.PlainLyrics, Lines: ParseLRC(result.SyncedLyrics)}
+		if lyrics.Text == "" {
+			texts := make([]string, len(lyrics.Lines))
+			for i, line := range lyrics.Lines {
+				texts[i] = line.Text
+			}
+			lyrics.Text = strings.Join(texts, "\n")
+		}
+		// Synced lyrics may hold nothing but metadata tags or blank lines.
+		if strings.TrimSpace(lyrics.Text) == "" {
+			continue
+		}
+		return lyrics, nil
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,161 @@
+// Package lyrics looks up song lyrics through pluggable providers.
+//
+// The Lavalink LavaLyrics plugin is used when the node has it installed, other providers such as LRCLIB
+// can be chained behind it as a fallback.
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+)
+
+var (
+	// ErrNotFound is returned when a provider has no lyrics for a track.
+	ErrNotFound = errors.New("no lyrics found")
+	// ErrUnsupported is returned when a provider is not available, e.g. the Lavalink node lacks the plugin.
+	ErrUnsupported = errors.New("lyrics provider not available")
+)
+
+// Line is a single line of synced lyrics.
+type Line struct {
+	// Timestamp is the position in the track at which the line starts.
+	Timestamp time.Duration
+	Text      string
+}
+
+// Lyrics are the lyrics of a track.
+type Lyrics struct {
+	// Provider is the name of the service the lyrics came from.
+	Provider string
+	// Text is the plain text of the lyrics.
+	Text string
+	// Lines are the synced lines, ordered by timestamp. They are empty when only plain text is available.
+	Lines []Line
+}
+
+// LineAt returns the index of the synced line being sung at position, or -1 before the first line.
+func (l *Lyrics) LineAt(position time.Duration) int {
+	return sort.Search(len(l.Lines), func(i int) bool {
+		return l.Lines[i].Timestamp > position
+	}) - 1
+}
+
+// Provider looks up the lyrics of a track.
+type Provider interface {
+	// Name is the name of the provider shown to users.
+	Name() string
+	// Lyrics returns the lyrics of track, or ErrNotFound.
+	Lyrics(ctx context.Context, track lavalink.Track) (*Lyrics, error)
+}
+
+// Chain returns a provider which asks each provider in order until one has lyrics for the track.
+func Chain(providers ...Provider) Provider {
+	return chain(providers)
+}
+
+type chain []Provider
+
+func (c chain) Name() string {
+	names := make([]string, len(c))
+	for i, provider := range c {
+		names[i] = provider.Name()
+	}
+	return strings.Join(names, ", ")
+}
+
+func (c chain) Lyrics(ctx context.Context, track lavalink.Track) (*Lyrics, error) {
+	var errs []error
+	for _, provider := range c {
+		lyrics, err := provider.Lyrics(ctx, track)
+		if err == nil {
+			return lyrics, nil
+		}
+		if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrUnsupported) {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return nil, ErrNotFound
+}
+
+var lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{2})(?:[.:](\d{1,3}))?]`)
+
+// ParseLRC parses synced lyrics in the LRC format. Lines without a timestamp, such as metadata tags, are skipped.
+func ParseLRC(lrc string) []Line {
+	var lines []Line
+	for _, raw := range strings.Split(lrc, "\n") {
+		raw = strings.TrimSpace(raw)
+		var timestamps []time.Duration
+		for {
+			match := lrcTimestamp.FindStringSubmatch(raw)
+			if match == nil {
+				break
+			}
+			minutes, _ := strconv.Atoi(match[1])
+			seconds, _ := strconv.Atoi(match[2])
+			timestamp := time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+			if match[3] != "" {
+				// Fractions are hundredths in most files, but some use milliseconds.
+				fraction, _ := strconv.Atoi(match[3] + strings.Repeat("0", 3-len(match[3])))
+				timestamp += time.Duration(fraction) * time.Millisecond
+			}
+			timestamps = append(timestamps, timestamp)
+			raw = raw[len(match[0]):]
+		}
+		for _, timestamp := range timestamps {
+			lines = append(lines, Line{Timestamp: timestamp, Text: strings.TrimSpace(raw)})
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp < lines[j].Timestamp
+	})
+	return lines
+}
+
+// Pages splits text into pages of at most size bytes, breaking between lines where possible.
+func Pages(text string, size int) []string {
+	var (
+		pages []string
+		page  strings.Builder
+	)
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		for len(line) > size {
+			if page.Len() > 0 {
+				pages = append(pages, page.String())
+				page.Reset()
+			}
+			cut := size
+			for cut > 0 && !isRuneStart(line[cut]) {
+				cut--
+			}
+			pages = append(pages, line[:cut])
+			line = line[cut:]
+		}
+		if page.Len() > 0 && page.Len()+1+len(line) > size {
+			pages = append(pages, page.String())
+			page.Reset()
+		}
+		if page.Len() > 0 {
+			page.WriteByte('\n')
+		}
+		page.WriteString(line)
+	}
+	if page.Len() > 0 {
+		pages = append(pages, page.String())
+	}
+	return pages
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
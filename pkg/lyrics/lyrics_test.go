@@ -0,0 +1,111 @@
+package lyrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProvider struct {
+	name   string
+	lyrics *Lyrics
+	err    error
+}
+
+func (s staticProvider) Name() string { return s.name }
+
+func (s staticProvider) Lyrics(context.Context, lavalink.Track) (*Lyrics, error) {
+	return s.lyrics, s.err
+}
+
+func Test_ParseLRC(t *testing.T) {
+	lines := ParseLRC("[ar:Artist]\n[00:12.50]First\n[01:02.123][00:05]Chorus\n[00:20.00]\nplain text")
+
+	assert.Equal(t, []Line{
+		{Timestamp: 5 * time.Second, Text: "Chorus"},
+		{Timestamp: 12*time.Second + 500*time.Millisecond, Text: "First"},
+		{Timestamp: 20 * time.Second, Text: ""},
+		{Timestamp: time.Minute + 2*time.Second + 123*time.Millisecond, Text: "Chorus"},
+	}, lines)
+}
+
+func Test_LineAt(t *testing.T) {
+	lyrics := &Lyrics{Lines: []Line{{Timestamp: time.Second}, {Timestamp: 3 * time.Second}}}
+
+	assert.Equal(t, -1, lyrics.LineAt(0))
+	assert.Equal(t, 0, lyrics.LineAt(time.Second))
+	assert.Equal(t, 0, lyrics.LineAt(2*time.Second))
+	assert.Equal(t, 1, lyrics.LineAt(time.Minute))
+}
+
+func Test_Pages(t *testing.T) {
+	assert.Equal(t, []string{"one\ntwo", "three", "four"}, Pages("one\ntwo\nthree\nfour\n", 9))
+	assert.Equal(t, []string{"abcd", "efgh", "ij"}, Pages("abcdefghij", 4))
+	assert.Equal(t, []string{"aé", "éb"}, Pages("aééb", 4), "runes are not split")
+}
+
+func Test_Chain(t *testing.T) {
+	found := &Lyrics{Provider: "second", Text: "text"}
+	failure := errors.New("boom")
+
+	result, err := Chain(
+		staticProvider{name: "unsupported", err: ErrUnsupported},
+		staticProvider{name: "missing", err: ErrNotFound},
+		staticProvider{name: "second", lyrics: found},
+	).Lyrics(context.Background(), lavalink.Track{})
+	require.NoError(t, err)
+	assert.Same(t, found, result)
+
+	_, err = Chain(staticProvider{name: "missing", err: ErrNotFound}).Lyrics(context.Background(), lavalink.Track{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = Chain(staticProvider{name: "broken", err: failure}, staticProvider{name: "missing", err: ErrNotFound}).Lyrics(context.Background(), lavalink.Track{})
+	assert.ErrorIs(t, err, failure)
+	assert.ErrorContains(t, err, "broken: boom")
+}
+
+func Test_LRCLIB(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/search", r.URL.Path)
+		assert.Equal(t, "Song", r.URL.Query().Get("track_name"))
+		assert.Equal(t, "Artist", r.URL.Query().Get("artist_name"))
+		_, _ = w.Write([]byte(`[{"instrumental":true},{"plainLyrics":"","syncedLyrics":"[00:01.00]Hello\n[00:02.00]World"}]`))
+	}))
+	defer server.Close()
+
+	result, err := LRCLIB{BaseURL: server.URL + "/"}.Lyrics(context.Background(), lavalink.Track{Info: lavalink.TrackInfo{Title: "Song", Author: "Artist"}})
+
+	require.NoError(t, err)
+	assert.Equal(t, "LRCLIB", result.Provider)
+	assert.Equal(t, "Hello\nWorld", result.Text)
+	assert.Len(t, result.Lines, 2)
+}
+
+func Test_LRCLIB_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	_, err := LRCLIB{BaseURL: server.URL}.Lyrics(context.Background(), lavalink.Track{Info: lavalink.TrackInfo{Title: "Song"}})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_LRCLIB_EmptyText(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`[{"plainLyrics":"","syncedLyrics":"[ar:Artist]\n[ti:Song]"},{"plainLyrics":" ","syncedLyrics":"[00:01.00]\n[00:02.00] "}]`))
+	}))
+	defer server.Close()
+
+	_, err := LRCLIB{BaseURL: server.URL}.Lyrics(context.Background(), lavalink.Track{Info: lavalink.TrackInfo{Title: "Song"}})
+
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,106 @@
+// Package metrics holds the Prometheus collectors exported by the bot.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric exported by the bot.
+const Namespace = "discord_music"
+
+// Command outcomes used for the outcome label of CommandsHandled.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeUnknown = "unknown"
+	// OutcomeUserError is recorded when a command fails because of the user, e.g. invalid input.
+	OutcomeUserError = "user_error"
+	// OutcomePanic is recorded when a command handler panics.
+	OutcomePanic = "panic"
+	// OutcomeThrottled is recorded when a command is refused by the rate limiter.
+	OutcomeThrottled = "throttled"
+)
+
+// Registry is the registry all bot metrics are registered with. A dedicated
+// registry is used so tests and embedders are not affected by the global one.
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	CommandsHandled = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "commands_handled_total",
+		Help:      "Number of application commands handled, by command name and outcome.",
+	}, []string{"command", "outcome"})
+
+	CommandsThrottled = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "commands_throttled_total",
+		Help:      "Number of application commands refused by the rate limiter, by command name.",
+	}, []string{"command"})
+
+	CommandDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "command_duration_seconds",
+		Help:      "Time taken to handle application commands, by command name.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"command"})
+
+	TrackStarts = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "track_starts_total",
+		Help:      "Number of tracks started.",
+	})
+
+	TrackEnds = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "track_ends_total",
+		Help:      "Number of tracks ended, by end reason.",
+	}, []string{"reason"})
+
+	TrackExceptions = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "track_exceptions_total",
+		Help:      "Number of track exceptions reported by Lavalink, by severity.",
+	}, []string{"severity"})
+
+	TrackStuck = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "track_stuck_total",
+		Help:      "Number of track stuck events reported by Lavalink.",
+	})
+
+	IdleDisconnects = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "idle_disconnects_total",
+		Help:      "Number of voice disconnects caused by an idle timeout, by idle reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Register adds additional collectors, such as ones reading live bot state, to Registry.
+func Register(cs ...prometheus.Collector) error {
+	for _, c := range cs {
+		if err := Registry.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler serving the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
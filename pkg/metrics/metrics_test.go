@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Register(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: Namespace, Name: "test_register"})
+	gauge.Set(2)
+
+	require.NoError(t, Register(gauge))
+	t.Cleanup(func() { Registry.Unregister(gauge) })
+
+	expected := `
+# HELP discord_music_test_register
+# TYPE discord_music_test_register gauge
+discord_music_test_register 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(Registry, strings.NewReader(expected), "discord_music_test_register"))
+	assert.Error(t, Register(gauge), "registering a collector twice")
+}
+
+func Test_Handler(t *testing.T) {
+	TrackStuck.Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "discord_music_track_stuck_total ")
+	assert.Contains(t, rec.Body.String(), "go_goroutines ")
+}
@@ -0,0 +1,198 @@
+// Package policy decides which tracks may be added to a guild's queue.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go-discord-music/pkg/format"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// ErrRejected is matched by RejectedError.
+var ErrRejected = errors.New("rejected by queue policy")
+
+// Policy limits what may be queued in a guild. The zero value admits everything.
+type Policy struct {
+	// MaxQueueLength is the maximum number of tracks waiting in the queue, 0 for no limit.
+	MaxQueueLength int `json:"max_queue_length,omitempty"`
+	// MaxTracksPerUser is the maximum number of tracks a user may have playing or queued, 0 for no limit.
+	MaxTracksPerUser int `json:"max_tracks_per_user,omitempty"`
+	// MaxTrackLength is the maximum length of a track, 0 for no limit. Streams are not limited by it.
+	MaxTrackLength lavalink.Duration `json:"max_track_length,omitempty"`
+	// DenyStreams rejects live streams.
+	DenyStreams bool `json:"deny_streams,omitempty"`
+	// AllowedSources lists the source managers tracks may come from, e.g. youtube. Empty allows all sources.
+	AllowedSources []string `json:"allowed_sources,omitempty"`
+	// Blocklist holds keywords and URLs which may not appear in a track's title, author or URI, ignoring case.
+	Blocklist []string `json:"blocklist,omitempty"`
+}
+
+// Empty reports whether the policy admits everything.
+func (p Policy) Empty() bool {
+	return p.MaxQueueLength == 0 && p.MaxTracksPerUser == 0 && p.MaxTrackLength == 0 && !p.DenyStreams &&
+		len(p.AllowedSources) == 0 && len(p.Blocklist) == 0
+}
+
+// Request describes tracks a user wants to add to a guild's queue.
+type Request struct {
+	// Requester is the user adding the tracks. 0 skips the per user limit, e.g. for tracks added by the bot itself.
+	Requester snowflake.ID
+	Tracks    []lavalink.Track
+	// Current is the track which is playing, if any.
+	Current *lavalink.Track
+	// Queue are the tracks already waiting in the queue.
+	Queue []lavalink.Track
+}
+
+// Rejection is a track which was not admitted and why.
+type Rejection struct {
+	Track  lavalink.Track
+	Reason string
+}
+
+func (r Rejection) String() string {
+	return fmt.Sprintf("`%s`: %s", r.Track.Info.Title, r.Reason)
+}
+
+// RejectedError is returned when none of the requested tracks were admitted.
+type RejectedError struct {
+	Rejections []Rejection
+}
+
+func (e *RejectedError) Error() string {
+	if len(e.Rejections) == 0 {
+		return ErrRejected.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrRejected, e.Rejections[0].Reason)
+}
+
+func (e *RejectedError) Unwrap() error {
+	return ErrRejected
+}
+
+// Admit returns the tracks of the request which the policy allows, in order, and the rejected ones. When nothing
+// is playing the first admitted track starts right away and does not take up a place in the queue.
+func (p Policy) Admit(request Request) ([]lavalink.Track, []Rejection) {
+	var admitted []lavalink.Track
+	var rejected []Rejection
+
+	queueSpace := -1
+	if p.MaxQueueLength > 0 {
+		queueSpace = max(p.MaxQueueLength-len(request.Queue), 0)
+		if request.Current == nil {
+			queueSpace++
+		}
+	}
+	userSpace := -1
+	if p.MaxTracksPerUser > 0 && request.Requester != 0 {
+		userSpace = max(p.MaxTracksPerUser-p.countRequested(request), 0)
+	}
+
+	for _, track := range request.Tracks {
+		reason := p.check(track)
+		switch {
+		case reason != "":
+		case queueSpace == 0:
+			reason = fmt.Sprintf("the queue is full, it holds at most %d tracks", p.MaxQueueLength)
+		case userSpace == 0:
+			reason = fmt.Sprintf("you can have at most %d tracks in the queue", p.MaxTracksPerUser)
+		}
+		if reason != "" {
+			rejected = append(rejected, Rejection{Track: track, Reason: reason})
+			continue
+		}
+		admitted = append(admitted, track)
+		queueSpace--
+		userSpace--
+	}
+	return admitted, rejected
+}
+
+// check returns why track may not be queued regardless of what else is queued, or an empty string.
+func (p Policy) check(track lavalink.Track) string {
+	info := track.Info
+	switch {
+	case info.IsStream && p.DenyStreams:
+		return "live streams are not allowed"
+	case !info.IsStream && p.MaxTrackLength > 0 && info.Length > p.MaxTrackLength:
+		return fmt.Sprintf("tracks may be at most %s long", format.Duration(p.MaxTrackLength))
+	case len(p.AllowedSources) > 0 && !slices.ContainsFunc(p.AllowedSources, func(source string) bool {
+		return strings.EqualFold(source, info.SourceName)
+	}):
+		return fmt.Sprintf("tracks from %s are not allowed", info.SourceName)
+	}
+	if p.blocked(info) {
+		return "it matches the blocklist"
+	}
+	return ""
+}
+
+// blocked reports whether info matches an entry of the blocklist.
+func (p Policy) blocked(info lavalink.TrackInfo) bool {
+	fields := []string{strings.ToLower(info.Title), strings.ToLower(info.Author)}
+	if info.URI != nil {
+		fields = append(fields, strings.ToLower(*info.URI))
+	}
+	for _, entry := range p.Blocklist {
+		needle := strings.ToLower(strings.TrimSpace(entry))
+		if needle == "" {
+			continue
+		}
+		for _, field := range fields {
+			if strings.Contains(field, needle) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p Policy) countRequested(request Request) int {
+	count := 0
+	if request.Current != nil && Requester(*request.Current) == request.Requester {
+		count++
+	}
+	for _, track := range request.Queue {
+		if Requester(track) == request.Requester {
+			count++
+		}
+	}
+	return count
+}
+
+type userData struct {
+	Requester string `json:"requester,omitempty"`
+}
+
+// WithRequester records who requested track in its user data, which Lavalink keeps while the track plays.
+func WithRequester(track lavalink.Track, requester snowflake.ID) lavalink.Track {
+	if requester == 0 {
+		return track
+	}
+	tagged, err := track.WithUserData(userData{Requester: requester.String()})
+	if err != nil {
+		return track
+	}
+	return tagged
+}
+
+// Requester returns who requested track, or 0 when it is not known.
+func Requester(track lavalink.Track) snowflake.ID {
+	if len(track.UserData) == 0 {
+		return 0
+	}
+	var data userData
+	if err := track.UserData.Unmarshal(&data); err != nil {
+		return 0
+	}
+	id, err := snowflake.Parse(data.Requester)
+	if err != nil {
+		return 0
+	}
+	return id
+}
@@ -0,0 +1,119 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+const user snowflake.ID = 2
+
+func track(title string, length lavalink.Duration) lavalink.Track {
+	uri := "https://example.com/" + title
+	return lavalink.Track{Encoded: title, Info: lavalink.TrackInfo{Title: title, Author: "Artist", Length: length, URI: &uri, SourceName: "youtube"}}
+}
+
+func reasons(rejected []Rejection) []string {
+	var result []string
+	for _, rejection := range rejected {
+		result = append(result, rejection.Reason)
+	}
+	return result
+}
+
+func Test_EmptyPolicyAdmitsEverything(t *testing.T) {
+	tracks := []lavalink.Track{track("a", lavalink.Hour), {Info: lavalink.TrackInfo{IsStream: true}}}
+
+	admitted, rejected := Policy{}.Admit(Request{Requester: user, Tracks: tracks})
+
+	assert.True(t, Policy{}.Empty())
+	assert.Equal(t, tracks, admitted)
+	assert.Empty(t, rejected)
+}
+
+func Test_MaxQueueLength(t *testing.T) {
+	policy := Policy{MaxQueueLength: 2}
+	tracks := []lavalink.Track{track("a", 0), track("b", 0), track("c", 0), track("d", 0)}
+
+	admitted, rejected := policy.Admit(Request{Tracks: tracks})
+	assert.Len(t, admitted, 3, "the first track plays right away when nothing is playing")
+	assert.Equal(t, []string{"the queue is full, it holds at most 2 tracks"}, reasons(rejected))
+
+	current := track("current", 0)
+	admitted, _ = policy.Admit(Request{Tracks: tracks, Current: &current, Queue: tracks[:1]})
+	assert.Len(t, admitted, 1)
+
+	admitted, _ = policy.Admit(Request{Tracks: tracks, Current: &current, Queue: tracks})
+	assert.Empty(t, admitted, "a queue which is already over the limit admits nothing")
+}
+
+func Test_MaxTracksPerUser(t *testing.T) {
+	policy := Policy{MaxTracksPerUser: 2}
+	current := WithRequester(track("current", 0), user)
+	queue := []lavalink.Track{WithRequester(track("other", 0), 3), track("unknown", 0)}
+
+	admitted, rejected := policy.Admit(Request{Requester: user, Tracks: []lavalink.Track{track("a", 0), track("b", 0)}, Current: &current, Queue: queue})
+	assert.Equal(t, []lavalink.Track{track("a", 0)}, admitted)
+	assert.Equal(t, []string{"you can have at most 2 tracks in the queue"}, reasons(rejected))
+
+	admitted, _ = policy.Admit(Request{Tracks: []lavalink.Track{track("a", 0), track("b", 0), track("c", 0)}, Current: &current})
+	assert.Len(t, admitted, 3, "tracks without a requester are not limited")
+}
+
+func Test_TrackChecks(t *testing.T) {
+	stream := track("radio", 0)
+	stream.Info.IsStream = true
+	soundcloud := track("sc", 0)
+	soundcloud.Info.SourceName = "soundcloud"
+	blockedURI := track("x", 0)
+	blockedURI.Info.URI = nil
+	blockedURI.Info.Author = "Bad Band"
+
+	policy := Policy{
+		MaxTrackLength: 10 * lavalink.Minute,
+		DenyStreams:    true,
+		AllowedSources: []string{"YouTube"},
+		Blocklist:      []string{"  ", "example.com/forbidden", "bad band"},
+	}
+	admitted, rejected := policy.Admit(Request{Requester: user, Tracks: []lavalink.Track{
+		track("ok", 10*lavalink.Minute), track("long", 11*lavalink.Minute), stream, soundcloud, track("forbidden", 0), blockedURI,
+	}})
+
+	assert.Equal(t, []lavalink.Track{track("ok", 10*lavalink.Minute)}, admitted)
+	assert.Equal(t, []string{
+		"tracks may be at most 10:00 long",
+		"live streams are not allowed",
+		"tracks from soundcloud are not allowed",
+		"it matches the blocklist",
+		"it matches the blocklist",
+	}, reasons(rejected))
+	assert.Equal(t, "`long`: tracks may be at most 10:00 long", rejected[0].String())
+}
+
+func Test_StreamsIgnoreMaxTrackLength(t *testing.T) {
+	stream := track("radio", lavalink.Hour)
+	stream.Info.IsStream = true
+
+	admitted, _ := Policy{MaxTrackLength: lavalink.Minute}.Admit(Request{Tracks: []lavalink.Track{stream}})
+
+	assert.Len(t, admitted, 1)
+}
+
+func Test_Requester(t *testing.T) {
+	assert.Equal(t, user, Requester(WithRequester(track("a", 0), user)))
+	assert.Equal(t, snowflake.ID(0), Requester(track("a", 0)))
+	assert.Equal(t, snowflake.ID(0), Requester(lavalink.Track{UserData: lavalink.RawData("{}")}))
+	assert.Equal(t, track("a", 0), WithRequester(track("a", 0), 0))
+}
+
+func Test_RejectedError(t *testing.T) {
+	err := error(&RejectedError{Rejections: []Rejection{{Reason: "live streams are not allowed"}}})
+
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.EqualError(t, err, "rejected by queue policy: live streams are not allowed")
+	var rejectedErr *RejectedError
+	assert.True(t, errors.As(err, &rejectedErr))
+}
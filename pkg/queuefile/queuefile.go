@@ -0,0 +1,160 @@
+// Package queuefile reads and writes queues as JSON or M3U files so they can be moved between servers and bots.
+//
+// JSON files keep the encoded tracks and their metadata. M3U files only list an identifier per track, usually its URI,
+// and can be read by other players.
+package queuefile
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+)
+
+// Version is the version of the JSON format written by Encode.
+const Version = 1
+
+// Format is the format of a queue file.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatM3U  Format = "m3u"
+)
+
+var ErrUnknownFormat = errors.New("unknown queue file format")
+
+// FormatFromName returns the format of the file called name based on its extension.
+func FormatFromName(name string) (Format, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".m3u", ".m3u8":
+		return FormatM3U, nil
+	default:
+		return "", ErrUnknownFormat
+	}
+}
+
+// FileName returns the name of an exported queue file in format.
+func (f Format) FileName() string {
+	return "queue." + string(f)
+}
+
+// Entry is a track read from a queue file.
+type Entry struct {
+	// Identifier resolves the track through Lavalink, usually its URI.
+	Identifier string
+	// Title is the title of the track, if the file has one.
+	Title string
+	// Track is the encoded track, only set for JSON files.
+	Track *lavalink.Track
+}
+
+// Name returns the title of the entry, or its identifier when it has no title.
+func (e Entry) Name() string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return e.Identifier
+}
+
+type file struct {
+	Version int              `json:"version"`
+	Tracks  []lavalink.Track `json:"tracks"`
+}
+
+// Identifier returns the identifier which resolves track again, its URI or a YouTube search for its author and title.
+func Identifier(track lavalink.Track) string {
+	if track.Info.URI != nil && *track.Info.URI != "" {
+		return *track.Info.URI
+	}
+	return lavalink.SearchTypeYouTube.Apply(track.Info.Author + " - " + track.Info.Title)
+}
+
+// Encode writes tracks to w in format.
+func Encode(w io.Writer, format Format, tracks []lavalink.Track) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(file{Version: Version, Tracks: tracks})
+	case FormatM3U:
+		var b strings.Builder
+		b.WriteString("#EXTM3U\n")
+		for _, track := range tracks {
+			seconds := int64(-1)
+			if !track.Info.IsStream {
+				seconds = int64(track.Info.Length.Seconds())
+			}
+			fmt.Fprintf(&b, "#EXTINF:%d,%s - %s\n%s\n", seconds, oneLine(track.Info.Author), oneLine(track.Info.Title), oneLine(Identifier(track)))
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// Decode reads the entries of a queue file in format from r, at most limit entries are read.
+func Decode(r io.Reader, format Format, limit int) ([]Entry, error) {
+	switch format {
+	case FormatJSON:
+		var f file
+		if err := json.NewDecoder(r).Decode(&f); err != nil {
+			return nil, fmt.Errorf("error parsing queue file: %w", err)
+		}
+		if f.Version != Version {
+			return nil, fmt.Errorf("unsupported queue file version %d", f.Version)
+		}
+		if len(f.Tracks) > limit {
+			f.Tracks = f.Tracks[:limit]
+		}
+		entries := make([]Entry, len(f.Tracks))
+		for i, track := range f.Tracks {
+			entries[i] = Entry{Identifier: Identifier(track), Title: track.Info.Title, Track: &track}
+		}
+		return entries, nil
+	case FormatM3U:
+		return decodeM3U(r, limit)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+func decodeM3U(r io.Reader, limit int) ([]Entry, error) {
+	var (
+		entries []Entry
+		title   string
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() && len(entries) < limit {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			// #EXTINF:<seconds>,<title>
+			if _, info, ok := strings.Cut(line, ","); ok {
+				title = strings.TrimSpace(info)
+			}
+		case strings.HasPrefix(line, "#"):
+		default:
+			entries = append(entries, Entry{Identifier: line, Title: title})
+			title = ""
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading queue file: %w", err)
+	}
+	return entries, nil
+}
+
+// oneLine replaces line breaks, which would end an M3U directive early.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,65 @@
+package queuefile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTracks() []lavalink.Track {
+	uri := "https://example.com/a"
+	return []lavalink.Track{
+		{Encoded: "a", Info: lavalink.TrackInfo{Title: "Track A", Author: "Artist", Length: 3 * lavalink.Minute, URI: &uri}, PluginInfo: lavalink.RawData("{}"), UserData: lavalink.RawData("{}")},
+		{Encoded: "b", Info: lavalink.TrackInfo{Title: "Radio\nLive", Author: "Station", IsStream: true}, PluginInfo: lavalink.RawData("{}"), UserData: lavalink.RawData("{}")},
+	}
+}
+
+func Test_FormatFromName(t *testing.T) {
+	for name, expected := range map[string]Format{"queue.json": FormatJSON, "Queue.M3U": FormatM3U, "list.m3u8": FormatM3U} {
+		format, err := FormatFromName(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, format, name)
+	}
+	_, err := FormatFromName("queue.txt")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func Test_JSON_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, FormatJSON, testTracks()))
+
+	entries, err := Decode(&buf, FormatJSON, 10)
+
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, Entry{Identifier: "https://example.com/a", Title: "Track A", Track: &testTracks()[0]}, entries[0])
+	assert.Equal(t, "ytsearch:Station - Radio\nLive", entries[1].Identifier)
+}
+
+func Test_JSON_UnsupportedVersion(t *testing.T) {
+	_, err := Decode(strings.NewReader(`{"version":2,"tracks":[]}`), FormatJSON, 10)
+	assert.ErrorContains(t, err, "unsupported queue file version 2")
+}
+
+func Test_M3U_Encode(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, FormatM3U, testTracks()))
+
+	assert.Equal(t, "#EXTM3U\n#EXTINF:180,Artist - Track A\nhttps://example.com/a\n#EXTINF:-1,Station - Radio Live\nytsearch:Station - Radio Live\n", buf.String())
+}
+
+func Test_M3U_Decode(t *testing.T) {
+	file := "#EXTM3U\r\n#EXTINF:180,Artist - Track A\r\nhttps://example.com/a\r\n\r\n# comment\r\nhttps://example.com/b\r\nhttps://example.com/c\r\n"
+
+	entries, err := Decode(strings.NewReader(file), FormatM3U, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Identifier: "https://example.com/a", Title: "Artist - Track A"},
+		{Identifier: "https://example.com/b"},
+	}, entries)
+}
@@ -0,0 +1,205 @@
+// Package ratelimit throttles repeated actions with token buckets, e.g. users spamming commands.
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalid is returned when a limit can't be parsed.
+var ErrInvalid = errors.New("invalid rate limit")
+
+// Off is the textual form of a limit which allows everything.
+const Off = "off"
+
+// Limit allows Burst calls at once, refilled evenly over Per. The zero value allows everything.
+//
+// Limits are written as burst/per, for example 3/10s, or off.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// Unlimited reports whether the limit allows everything.
+func (l Limit) Unlimited() bool {
+	return l.Burst <= 0 || l.Per <= 0
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return Off
+	}
+	return fmt.Sprintf("%d/%s", l.Burst, l.Per)
+}
+
+// MarshalText encodes the limit as burst/per.
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText decodes a limit written as burst/per or off.
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// interval is the time it takes to refill a single token.
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Burst)
+}
+
+// Parse parses a limit written as burst/per, e.g. 3/10s, or off.
+func Parse(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, Off) {
+		return Limit{}, nil
+	}
+	burst, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w: %q, use burst/per like 3/10s", ErrInvalid, s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(burst))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q has an invalid burst", ErrInvalid, s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%w: %q has an invalid period", ErrInvalid, s)
+	}
+	return Limit{Burst: n, Per: d}, nil
+}
+
+// Limits maps names, such as command names, to their limit. The Default entry applies to names without their own.
+type Limits map[string]Limit
+
+// Default is the Limits key applying to every name without its own limit.
+const Default = "*"
+
+// ParseLimits parses comma separated name=limit pairs, e.g. play=3/10s,*=5/5s.
+func ParseLimits(s string) (Limits, error) {
+	limits := make(Limits)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%w: %q, use name=burst/per", ErrInvalid, entry)
+		}
+		limit, err := Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		limits[name] = limit
+	}
+	return limits, nil
+}
+
+// Lookup returns the limit of name, falling back to the Default entry.
+func (l Limits) Lookup(name string) (Limit, bool) {
+	if limit, ok := l[name]; ok {
+		return limit, true
+	}
+	limit, ok := l[Default]
+	return limit, ok
+}
+
+func (l Limits) String() string {
+	names := slices.Sorted(maps.Keys(l))
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entries[i] = name + "=" + l[name].String()
+	}
+	return strings.Join(entries, ",")
+}
+
+// sweepInterval is how often buckets which have refilled completely are dropped.
+const sweepInterval = time.Minute
+
+// bucket is the token bucket of one key.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled completely.
+	full time.Time
+}
+
+// Limiter holds a token bucket per key. It is safe for concurrent use.
+type Limiter struct {
+	now       func() time.Time
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New returns a limiter reading the time from now, time.Now if nil.
+func New(now func() time.Time) *Limiter {
+	if now == nil {
+		now = time.Now
+	}
+	return &Limiter{
+		now:       now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: now(),
+	}
+}
+
+// Allow takes a token from the bucket of key, which holds up to limit.Burst tokens. When the bucket is empty it
+// returns false and how long it takes until the next token is available.
+func (l *Limiter) Allow(key string, limit Limit) (bool, time.Duration) {
+	if limit.Unlimited() {
+		return true, 0
+	}
+	now := l.now()
+	interval := limit.interval()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	// Limits may change between calls, so the bucket is refilled and capped with the current one.
+	b.tokens = min(b.tokens+float64(now.Sub(b.updated))/float64(interval), float64(limit.Burst))
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) * float64(interval))
+	}
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(limit.Burst) - b.tokens) * float64(interval)))
+	return true, 0
+}
+
+// Len returns the number of buckets held.
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// sweep drops buckets which have refilled completely, as they are equivalent to a new bucket.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	maps.DeleteFunc(l.buckets, func(_ string, b *bucket) bool {
+		return !now.Before(b.full)
+	})
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func Test_AllowBurstThenThrottle(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := New(clock.Now)
+	limit := Limit{Burst: 2, Per: 10 * time.Second}
+
+	for range 2 {
+		ok, _ := l.Allow("a", limit)
+		require.True(t, ok)
+	}
+	ok, retryAfter := l.Allow("a", limit)
+	assert.False(t, ok)
+	assert.Equal(t, 5*time.Second, retryAfter)
+
+	ok, _ = l.Allow("b", limit)
+	assert.True(t, ok, "keys have their own buckets")
+
+	clock.now = clock.now.Add(3 * time.Second)
+	ok, retryAfter = l.Allow("a", limit)
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, retryAfter)
+
+	clock.now = clock.now.Add(2 * time.Second)
+	ok, _ = l.Allow("a", limit)
+	assert.True(t, ok)
+}
+
+func Test_AllowUnlimited(t *testing.T) {
+	l := New(nil)
+
+	for range 100 {
+		ok, _ := l.Allow("a", Limit{})
+		require.True(t, ok)
+	}
+	assert.Zero(t, l.Len())
+}
+
+func Test_SweepDropsRefilledBuckets(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := New(clock.Now)
+
+	l.Allow("short", Limit{Burst: 1, Per: time.Second})
+	l.Allow("long", Limit{Burst: 1, Per: time.Hour})
+	clock.now = clock.now.Add(sweepInterval)
+	l.Allow("new", Limit{Burst: 1, Per: time.Second})
+
+	assert.Equal(t, 2, l.Len())
+	ok, _ := l.Allow("long", Limit{Burst: 1, Per: time.Hour})
+	assert.False(t, ok)
+}
+
+func Test_Parse(t *testing.T) {
+	limit, err := Parse(" 3/10s ")
+	require.NoError(t, err)
+	assert.Equal(t, Limit{Burst: 3, Per: 10 * time.Second}, limit)
+	assert.Equal(t, "3/10s", limit.String())
+
+	limit, err = Parse("OFF")
+	require.NoError(t, err)
+	assert.True(t, limit.Unlimited())
+
+	for _, input := range []string{"", "3", "0/10s", "-1/10s", "3/0s", "3/abc", "a/10s"} {
+		_, err := Parse(input)
+		assert.ErrorIs(t, err, ErrInvalid, input)
+	}
+}
+
+func Test_ParseLimits(t *testing.T) {
+	limits, err := ParseLimits("play=3/10s, *=5/5s,skip=off,")
+	require.NoError(t, err)
+	assert.Equal(t, Limits{
+		"play": {Burst: 3, Per: 10 * time.Second},
+		"skip": {},
+		"*":    {Burst: 5, Per: 5 * time.Second},
+	}, limits)
+	assert.Equal(t, "*=5/5s,play=3/10s,skip=off", limits.String())
+
+	limit, ok := limits.Lookup("pause")
+	assert.True(t, ok)
+	assert.Equal(t, limits[Default], limit)
+
+	_, err = ParseLimits("play")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func Test_LimitJSON(t *testing.T) {
+	data, err := json.Marshal(Limits{"play": {Burst: 3, Per: 10 * time.Second}})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"play":"3/10s"}`, string(data))
+
+	var limits Limits
+	require.NoError(t, json.Unmarshal(data, &limits))
+	assert.Equal(t, Limits{"play": {Burst: 3, Per: 10 * time.Second}}, limits)
+}
@@ -0,0 +1,236 @@
+// Package store persists bot settings in a JSON file.
+//
+// The whole state is small, so it is kept in memory and rewritten atomically on every change.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"go-discord-music/pkg/policy"
+	"go-discord-music/pkg/ratelimit"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/disgoorg/snowflake/v2"
+)
+
+// FileName is the name of the state file inside the data directory.
+const FileName = "state.json"
+
+// AlwaysOn is the 24/7 configuration of a guild.
+type AlwaysOn struct {
+	// ChannelID is the voice channel the bot stays connected to.
+	ChannelID snowflake.ID `json:"channel_id"`
+	// Fallback is an identifier, such as a playlist or radio stream URL, played whenever the queue is empty.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// Playlist is a saved list of tracks. Tracks keep their encoding so they can be played without searching again.
+type Playlist struct {
+	Name string `json:"name"`
+	// CreatedBy is the user who saved the playlist.
+	CreatedBy snowflake.ID     `json:"created_by"`
+	Tracks    []lavalink.Track `json:"tracks"`
+}
+
+// Playlists are saved playlists keyed by PlaylistKey of their name.
+type Playlists map[string]Playlist
+
+func (p Playlists) clone() Playlists {
+	playlists := make(Playlists, len(p))
+	maps.Copy(playlists, p)
+	return playlists
+}
+
+// PlaylistKey returns the key of the playlist called name, playlist names are case-insensitive.
+func PlaylistKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// GuildSettings are the persisted settings of a guild.
+type GuildSettings struct {
+	AlwaysOn *AlwaysOn `json:"always_on,omitempty"`
+	// Playlists are the playlists shared with the whole guild.
+	Playlists Playlists `json:"playlists,omitempty"`
+	// QueuePolicy limits what may be queued in the guild.
+	QueuePolicy *policy.Policy `json:"queue_policy,omitempty"`
+	// RateLimits override the bot's command rate limits in the guild, by command name.
+	RateLimits ratelimit.Limits `json:"rate_limits,omitempty"`
+}
+
+func (s GuildSettings) empty() bool {
+	return s.AlwaysOn == nil && len(s.Playlists) == 0 && s.QueuePolicy == nil && len(s.RateLimits) == 0
+}
+
+// UserSettings are the persisted settings of a user, shared across guilds.
+type UserSettings struct {
+	// Playlists are the user's personal playlists.
+	Playlists Playlists `json:"playlists,omitempty"`
+}
+
+func (s UserSettings) empty() bool {
+	return len(s.Playlists) == 0
+}
+
+type state struct {
+	Guilds map[snowflake.ID]GuildSettings `json:"guilds"`
+	Users  map[snowflake.ID]UserSettings  `json:"users,omitempty"`
+}
+
+// Store holds the bot state. The zero value is not usable, use Open or NewMemory.
+//
+// Settings returned by the store are shared and must not be modified, use UpdateGuild or UpdateUser instead.
+type Store struct {
+	path  string
+	mu    sync.RWMutex
+	state state
+}
+
+// NewMemory returns a store which is not persisted.
+func NewMemory() *Store {
+	return &Store{state: state{
+		Guilds: make(map[snowflake.ID]GuildSettings),
+		Users:  make(map[snowflake.ID]UserSettings),
+	}}
+}
+
+// Open loads the store from FileName in dir, creating the directory if needed. A missing file is an empty store.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating data directory: %w", err)
+	}
+	s := NewMemory()
+	s.path = filepath.Join(dir, FileName)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %w", err)
+	}
+	if err = json.Unmarshal(data, &s.state); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %w", s.path, err)
+	}
+	if s.state.Guilds == nil {
+		s.state.Guilds = make(map[snowflake.ID]GuildSettings)
+	}
+	if s.state.Users == nil {
+		s.state.Users = make(map[snowflake.ID]UserSettings)
+	}
+	return s, nil
+}
+
+// Persistent reports whether changes are written to disk.
+func (s *Store) Persistent() bool {
+	return s.path != ""
+}
+
+// Guild returns the settings of the guild.
+func (s *Store) Guild(guildID snowflake.ID) GuildSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.Guilds[guildID]
+}
+
+// Guilds returns the settings of all guilds with settings.
+func (s *Store) Guilds() map[snowflake.ID]GuildSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	guilds := make(map[snowflake.ID]GuildSettings, len(s.state.Guilds))
+	for guildID, settings := range s.state.Guilds {
+		guilds[guildID] = settings
+	}
+	return guilds
+}
+
+// UpdateGuild applies fn to the settings of the guild and persists the result. When fn or persisting fails, the
+// settings are left unchanged and the error is returned.
+// The playlists passed to fn are a non-nil copy which fn may modify in place.
+func (s *Store) UpdateGuild(guildID snowflake.ID, fn func(settings *GuildSettings) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings := s.state.Guilds[guildID]
+	settings.Playlists = settings.Playlists.clone()
+	if err := fn(&settings); err != nil {
+		return err
+	}
+	next := s.state
+	next.Guilds = maps.Clone(s.state.Guilds)
+	if settings.empty() {
+		delete(next.Guilds, guildID)
+	} else {
+		next.Guilds[guildID] = settings
+	}
+	return s.commit(next)
+}
+
+// User returns the settings of the user.
+func (s *Store) User(userID snowflake.ID) UserSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.Users[userID]
+}
+
+// UpdateUser applies fn to the settings of the user and persists the result. When fn or persisting fails, the
+// settings are left unchanged and the error is returned.
+// The playlists passed to fn are a non-nil copy which fn may modify in place.
+func (s *Store) UpdateUser(userID snowflake.ID, fn func(settings *UserSettings) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings := s.state.Users[userID]
+	settings.Playlists = settings.Playlists.clone()
+	if err := fn(&settings); err != nil {
+		return err
+	}
+	next := s.state
+	next.Users = maps.Clone(s.state.Users)
+	if settings.empty() {
+		delete(next.Users, userID)
+	} else {
+		next.Users[userID] = settings
+	}
+	return s.commit(next)
+}
+
+// commit persists next and makes it the current state once it was written. The caller must hold mu.
+func (s *Store) commit(next state) error {
+	if err := s.save(next); err != nil {
+		return err
+	}
+	s.state = next
+	return nil
+}
+
+// save writes st to a temporary file and renames it over the state file.
+func (s *Store) save(st state) error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), FileName+".*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("error writing state file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("error replacing state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disgoorg/disgolink/v3/lavalink"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Open_MissingFileIsEmpty(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "nested"))
+	require.NoError(t, err)
+	assert.True(t, s.Persistent())
+	assert.Empty(t, s.Guilds())
+}
+
+func Test_UpdateGuild_Persists(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	require.NoError(t, err)
+
+	require.NoError(t, s.UpdateGuild(1, func(settings *GuildSettings) error {
+		settings.AlwaysOn = &AlwaysOn{ChannelID: 2, Fallback: "https://example.com/radio"}
+		return nil
+	}))
+
+	reopened, err := Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, &AlwaysOn{ChannelID: 2, Fallback: "https://example.com/radio"}, reopened.Guild(1).AlwaysOn)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files are cleaned up")
+}
+
+func Test_UpdateGuild_RemovesEmptySettings(t *testing.T) {
+	s := NewMemory()
+	require.NoError(t, s.UpdateGuild(1, func(settings *GuildSettings) error {
+		settings.AlwaysOn = &AlwaysOn{ChannelID: 2}
+		return nil
+	}))
+	require.NoError(t, s.UpdateGuild(1, func(settings *GuildSettings) error {
+		settings.AlwaysOn = nil
+		return nil
+	}))
+
+	assert.False(t, s.Persistent())
+	assert.Empty(t, s.Guilds())
+}
+
+func Test_Open_InvalidFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, FileName), []byte("{"), 0o600))
+
+	_, err := Open(dir)
+	assert.Error(t, err)
+}
+
+func Test_UpdateUser_Persists(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	require.NoError(t, err)
+
+	track := lavalink.Track{Encoded: "a", PluginInfo: lavalink.RawData("{}"), UserData: lavalink.RawData("{}")}
+	require.NoError(t, s.UpdateUser(2, func(settings *UserSettings) error {
+		settings.Playlists[PlaylistKey(" Road Trip ")] = Playlist{Name: "Road Trip", CreatedBy: 2, Tracks: []lavalink.Track{track}}
+		return nil
+	}))
+
+	reopened, err := Open(dir)
+	require.NoError(t, err)
+	assert.Equal(t, Playlists{"road trip": {Name: "Road Trip", CreatedBy: 2, Tracks: []lavalink.Track{track}}}, reopened.User(2).Playlists)
+}
+
+func Test_UpdateGuild_CopiesPlaylists(t *testing.T) {
+	s := NewMemory()
+	require.NoError(t, s.UpdateGuild(1, func(settings *GuildSettings) error {
+		settings.Playlists["a"] = Playlist{Name: "a"}
+		return nil
+	}))
+	before := s.Guild(1).Playlists
+
+	require.NoError(t, s.UpdateGuild(1, func(settings *GuildSettings) error {
+		delete(settings.Playlists, "a")
+		return nil
+	}))
+
+	assert.Contains(t, before, "a", "settings returned earlier are not modified")
+	assert.Empty(t, s.Guilds())
+}
+
+func Test_UpdateGuild_ErrorKeepsState(t *testing.T) {
+	s := NewMemory()
+	require.NoError(t, s.UpdateGuild(1, func(settings *GuildSettings) error {
+		settings.Playlists["a"] = Playlist{Name: "a"}
+		return nil
+	}))
+
+	err := s.UpdateGuild(1, func(settings *GuildSettings) error {
+		delete(settings.Playlists, "a")
+		settings.AlwaysOn = &AlwaysOn{ChannelID: 2}
+		return errors.New("rejected")
+	})
+
+	assert.EqualError(t, err, "rejected")
+	assert.Equal(t, GuildSettings{Playlists: Playlists{"a": {Name: "a"}}}, s.Guild(1))
+}
+
+func Test_UpdateUser_SaveFailureKeepsState(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateUser(2, func(settings *UserSettings) error {
+		settings.Playlists["a"] = Playlist{Name: "a"}
+		return nil
+	}))
+	require.NoError(t, os.RemoveAll(dir))
+
+	err = s.UpdateUser(2, func(settings *UserSettings) error {
+		settings.Playlists["b"] = Playlist{Name: "b"}
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, Playlists{"a": {Name: "a"}}, s.User(2).Playlists)
+}
@@ -0,0 +1,106 @@
+// Package timestamp parses the positions users type to seek within a track, such as 1:23, 01:02:03, 90s or the
+// relative offsets +30s and -1m.
+package timestamp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned when a timestamp can't be parsed.
+var ErrInvalid = errors.New("invalid timestamp")
+
+// Position is a parsed timestamp.
+type Position struct {
+	// Offset is the absolute position, or the signed offset from the current position when Relative is set.
+	Offset   time.Duration
+	Relative bool
+}
+
+// Parse parses s as a clock timestamp (1:23, 01:02:03), a duration with units (90s, 1m30s, 500ms) or a bare
+// number of unit. A leading + or - makes the position relative to the current one.
+func Parse(s string, unit time.Duration) (Position, error) {
+	s = strings.TrimSpace(s)
+	var position Position
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "+"):
+		position.Relative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "-"):
+		position.Relative = true
+		sign = -1
+		s = s[1:]
+	}
+
+	offset, err := parseUnsigned(strings.TrimSpace(s), unit)
+	if err != nil {
+		return Position{}, err
+	}
+	position.Offset = sign * offset
+	return position, nil
+}
+
+// Resolve returns the absolute position p refers to when playback is at current. Relative positions never resolve
+// to before the start of the track.
+func (p Position) Resolve(current time.Duration) time.Duration {
+	if !p.Relative {
+		return p.Offset
+	}
+	return max(current+p.Offset, 0)
+}
+
+func parseUnsigned(s string, unit time.Duration) (time.Duration, error) {
+	switch {
+	case s == "" || strings.HasPrefix(s, "+") || strings.HasPrefix(s, "-"):
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	case strings.Contains(s, ":"):
+		return parseClock(s)
+	}
+	if number, err := strconv.ParseFloat(s, 64); err == nil && isDigit(s[0]) {
+		if number*float64(unit) >= math.MaxInt64 {
+			return 0, fmt.Errorf("%w: %q is too large", ErrInvalid, s)
+		}
+		return time.Duration(number * float64(unit)), nil
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	return duration, nil
+}
+
+// parseClock parses [hh:]mm:ss[.fff]. The leading part may exceed its usual range, so 90:00 is 90 minutes.
+func parseClock(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("%w: %q has too many parts", ErrInvalid, s)
+	}
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds < 0 || seconds >= 60 || !isDigit(parts[len(parts)-1][0]) {
+		return 0, fmt.Errorf("%w: %q has invalid seconds", ErrInvalid, s)
+	}
+	total := time.Duration(seconds * float64(time.Second))
+
+	units := []time.Duration{time.Minute, time.Hour}
+	for i, part := range parts[:len(parts)-1] {
+		value, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q has invalid numbers", ErrInvalid, s)
+		}
+		// Only the first part may be out of its usual range.
+		if i > 0 && value >= 60 {
+			return 0, fmt.Errorf("%w: %q has invalid minutes", ErrInvalid, s)
+		}
+		total += time.Duration(value) * units[len(parts)-2-i]
+	}
+	return total, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
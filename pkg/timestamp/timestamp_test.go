@@ -0,0 +1,58 @@
+package timestamp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Position
+	}{
+		{"1:23", Position{Offset: time.Minute + 23*time.Second}},
+		{"01:02:03", Position{Offset: time.Hour + 2*time.Minute + 3*time.Second}},
+		{"90:00", Position{Offset: 90 * time.Minute}},
+		{"0:05.5", Position{Offset: 5500 * time.Millisecond}},
+		{"90s", Position{Offset: 90 * time.Second}},
+		{"1m30s", Position{Offset: 90 * time.Second}},
+		{"500ms", Position{Offset: 500 * time.Millisecond}},
+		{"45", Position{Offset: 45 * time.Second}},
+		{" 1.5 ", Position{Offset: 1500 * time.Millisecond}},
+		{"+30s", Position{Offset: 30 * time.Second, Relative: true}},
+		{"-1m", Position{Offset: -time.Minute, Relative: true}},
+		{"- 0:10", Position{Offset: -10 * time.Second, Relative: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := Parse(tt.input, time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_ParseUnit(t *testing.T) {
+	got, err := Parse("2", time.Minute)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Minute, got.Offset)
+}
+
+func Test_ParseInvalid(t *testing.T) {
+	for _, input := range []string{"", "+", "--1s", "abc", "1:60", "1:2:3:4", "1:75:00", "a:10", "1:-5", "1:+5", "-5s:", "Inf", "NaN", "1e300"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := Parse(input, time.Second)
+			assert.ErrorIs(t, err, ErrInvalid)
+		})
+	}
+}
+
+func Test_Resolve(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Position{Offset: 10 * time.Second}.Resolve(time.Minute))
+	assert.Equal(t, 90*time.Second, Position{Offset: 30 * time.Second, Relative: true}.Resolve(time.Minute))
+	assert.Equal(t, time.Duration(0), Position{Offset: -time.Minute, Relative: true}.Resolve(10*time.Second))
+}